@@ -0,0 +1,204 @@
+// zhuyi-client-gen generates the methods of client.Client from the
+// operations of the OpenAPI spec, see pkg/openapi. It's run by
+// go generate in pkg/client.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/pkg/openapi"
+)
+
+// pathParamExtractor matches the {parameters} of a path
+var pathParamExtractor = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+func main() {
+	out := flag.String("out", "client_gen.go", "file to write the client to")
+	flag.Parse()
+
+	src, err := generate(openapi.Operations)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(ops []openapi.Operation) ([]byte, error) {
+	imports := map[string]bool{"context": true, "net/url": true}
+	var b bytes.Buffer
+
+	for _, op := range ops {
+		if op.Response != nil || op.Request != nil {
+			imports["github.com/kraem/zhuyi-go/pkg/payloads"] = true
+		}
+		if op.Response == nil || op.RequestType != "" {
+			imports["io"] = true
+		}
+		for _, p := range op.Params {
+			if p.In == "query" && p.Type == "integer" {
+				imports["strconv"] = true
+			}
+		}
+		writeOperation(&b, op)
+	}
+
+	names := make([]string, 0, len(imports))
+	for i := range imports {
+		names = append(names, i)
+	}
+	sort.Strings(names)
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by zhuyi-client-gen. DO NOT EDIT.\n\npackage client\n\nimport (\n")
+	// the standard library first, then the module
+	for _, std := range []bool{true, false} {
+		if !std {
+			src.WriteString("\n")
+		}
+		for _, i := range names {
+			if !strings.Contains(i, ".") == std {
+				fmt.Fprintf(&src, "\t%q\n", i)
+			}
+		}
+	}
+	src.WriteString(")\n")
+	b.WriteTo(&src)
+
+	return format.Source(src.Bytes())
+}
+
+func writeOperation(b *bytes.Buffer, op openapi.Operation) {
+	query := make([]openapi.Param, 0)
+	for _, p := range op.Params {
+		if p.In == "query" {
+			query = append(query, p)
+		}
+	}
+
+	if len(query) > 0 {
+		fmt.Fprintf(b, "\n// %vParams are the query parameters of %v\ntype %vParams struct {\n", op.ID, op.ID, op.ID)
+		for _, p := range query {
+			fmt.Fprintf(b, "\t// %v\n\t%v %v\n", p.Description, goName(p.Name), goType(p.Type))
+		}
+		b.WriteString("}\n")
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, p := range op.Params {
+		if p.In == "path" {
+			args = append(args, p.Name+" string")
+		}
+	}
+	if len(query) > 0 {
+		args = append(args, fmt.Sprintf("p %vParams", op.ID))
+	}
+	switch {
+	case op.Request != nil:
+		args = append(args, "req payloads."+typeName(op.Request))
+	case op.RequestType != "":
+		args = append(args, "body io.Reader")
+	}
+
+	result := "io.ReadCloser"
+	if op.Response != nil {
+		result = "*payloads." + typeName(op.Response)
+	}
+
+	summary := op.Summary
+	if summary != "" {
+		summary = strings.ToLower(summary[:1]) + summary[1:]
+	}
+	fmt.Fprintf(b, "\n// %v %v, %v %v\n", op.ID, summary, op.Method, op.Path)
+	fmt.Fprintf(b, "func (c *Client) %v(%v) (%v, error) {\n", op.ID, strings.Join(args, ", "), result)
+
+	path := pathExpr(op.Path)
+	if op.Network {
+		path = "c.networkPath(" + path + ")"
+	}
+	fmt.Fprintf(b, "\tpath := %v\n", path)
+
+	b.WriteString("\tq := url.Values{}\n")
+	for _, p := range query {
+		field := "p." + goName(p.Name)
+		switch p.Type {
+		case "boolean":
+			fmt.Fprintf(b, "\tif %v {\n\t\tq.Set(%q, \"true\")\n\t}\n", field, p.Name)
+		case "integer":
+			fmt.Fprintf(b, "\tif %v != 0 {\n\t\tq.Set(%q, strconv.Itoa(%v))\n\t}\n", field, p.Name, field)
+		default:
+			fmt.Fprintf(b, "\tif %v != \"\" {\n\t\tq.Set(%q, %v)\n\t}\n", field, p.Name, field)
+		}
+	}
+
+	if op.Response == nil {
+		body, contentType := "nil", `""`
+		if op.RequestType != "" {
+			body, contentType = "body", fmt.Sprintf("%q", op.RequestType)
+		}
+		fmt.Fprintf(b, "\treturn c.doRaw(ctx, %q, path, q, %v, %v)\n}\n", op.Method, contentType, body)
+		return
+	}
+
+	fmt.Fprintf(b, "\tvar resp payloads.%v\n", typeName(op.Response))
+	switch {
+	case op.RequestType != "":
+		fmt.Fprintf(b, "\tif err := c.doDecode(ctx, %q, path, q, %q, body, &resp); err != nil {\n", op.Method, op.RequestType)
+	case op.Request != nil:
+		fmt.Fprintf(b, "\tif err := c.doJSON(ctx, %q, path, q, req, &resp); err != nil {\n", op.Method)
+	default:
+		fmt.Fprintf(b, "\tif err := c.doJSON(ctx, %q, path, q, nil, &resp); err != nil {\n", op.Method)
+	}
+	b.WriteString("\t\treturn nil, err\n\t}\n")
+	b.WriteString("\treturn &resp, nil\n}\n")
+}
+
+// pathExpr returns the Go expression of a path, its parameters escaped
+func pathExpr(path string) string {
+	parts := make([]string, 0)
+	last := 0
+	for _, m := range pathParamExtractor.FindAllStringSubmatchIndex(path, -1) {
+		if m[0] > last {
+			parts = append(parts, fmt.Sprintf("%q", path[last:m[0]]))
+		}
+		parts = append(parts, "url.PathEscape("+path[m[2]:m[3]]+")")
+		last = m[1]
+	}
+	if last < len(path) {
+		parts = append(parts, fmt.Sprintf("%q", path[last:]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func typeName(v interface{}) string {
+	return reflect.TypeOf(v).Name()
+}
+
+// goName turns a parameter name, e.g. dry-run, into a field name, DryRun
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' })
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func goType(t string) string {
+	switch t {
+	case "boolean":
+		return "bool"
+	case "integer":
+		return "int"
+	}
+	return "string"
+}
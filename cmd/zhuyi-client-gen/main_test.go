@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kraem/zhuyi-go/pkg/openapi"
+)
+
+// TestClientIsGenerated fails when the operations of the spec changed
+// without running go generate in pkg/client
+func TestClientIsGenerated(t *testing.T) {
+	want, err := generate(openapi.Operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile("../../pkg/client/client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("pkg/client/client_gen.go is out of date, run go generate ./pkg/client")
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"encoding/json"
 	"flag"
 	"os"
+	"strings"
 
-	"github.com/kraem/zhuyi-go/pkg/payloads"
 	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
 )
 
-func writeOutput(fileName *string, err error) {
-	r := payloads.NewAppendResponse(fileName, err)
+func writeOutput(fileName *string, changed []string, err error) {
+	r := payloads.NewAppendResponse(fileName, changed, err)
 	w := os.Stdout
 	if err != nil {
 		w = os.Stderr
@@ -18,19 +19,44 @@ func writeOutput(fileName *string, err error) {
 	json.NewEncoder(w).Encode(r)
 }
 
-func main() {
-	c, err := network.NewConfig()
+func create(args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	var title = fs.String("title", "", "title of the node")
+	var body = fs.String("body", "", "body of the node")
+	var tmpl = fs.String("template", "", "name of the template in NETWORK_PATH/.templates to create the node from")
+	var tags = fs.String("tags", "", "comma separated tags of the node")
+	var parent = fs.String("parent", "", "file name of an existing node to link the node from")
+	var heading = fs.String("heading", "", "heading in the parent to put the link under")
+	var position = fs.String("position", network.PositionLast, "first or last in the list of links in the parent")
+	c, err := network.NewConfig(fs, args)
 	if err != nil {
-		writeOutput(nil, err)
+		writeOutput(nil, nil, err)
 		os.Exit(1)
 	}
 
-	// TODO help msgs
-	var title = flag.String("title", "", "help message for flag n")
-	var body = flag.String("body", "", "help message for flag n")
-	flag.Parse()
+	fileName, changed, err := c.CreateNodeFrom(network.NodeOptions{
+		Title:          *title,
+		Body:           *body,
+		Template:       *tmpl,
+		Tags:           splitTags(*tags),
+		Parent:         *parent,
+		ParentHeading:  *heading,
+		ParentPosition: *position,
+	})
+	if err != nil {
+		writeOutput(nil, nil, err)
+		os.Exit(1)
+	}
 
-	fileName, err := c.CreateNode(*title, *body)
+	writeOutput(&fileName, changed, nil)
+}
 
-	writeOutput(&fileName, nil)
+func splitTags(s string) []string {
+	tags := make([]string, 0)
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
 }
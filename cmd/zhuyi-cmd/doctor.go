@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+func doctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	var asJSON = fs.Bool("json", false, "print the report as json")
+	var resp payloads.DiagnosticsResponse
+
+	var d *network.DiagnosticsReport
+	c, err := network.NewConfig(fs, args)
+	if err == nil {
+		d, err = c.Diagnostics()
+	}
+	if err != nil {
+		if *asJSON {
+			errString := err.Error()
+			resp.Error = &errString
+			json.NewEncoder(os.Stderr).Encode(resp)
+		} else {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		resp.Payload.Diagnostics = d
+		json.NewEncoder(os.Stdout).Encode(resp)
+		return
+	}
+	printDiagnostics(os.Stdout, d)
+}
+
+func printDiagnostics(w io.Writer, d *network.DiagnosticsReport) {
+	fmt.Fprintf(w, "cycles (%d):\n", len(d.Cycles))
+	for _, c := range d.Cycles {
+		fmt.Fprintf(w, "  %s\n", strings.Join(c.Path, " -> "))
+	}
+
+	fmt.Fprintf(w, "dead ends (%d):\n", len(d.DeadEnds))
+	for _, f := range d.DeadEnds {
+		fmt.Fprintf(w, "  %s\n", f)
+	}
+
+	fmt.Fprintf(w, "only linking to themselves (%d):\n", len(d.SelfLinkedOnly))
+	for _, f := range d.SelfLinkedOnly {
+		fmt.Fprintf(w, "  %s\n", f)
+	}
+
+	for _, h := range d.Histograms {
+		fmt.Fprintf(w, "depth from %s:\n", h.Root)
+		for depth, n := range h.Depths {
+			fmt.Fprintf(w, "  %3d: %d\n", depth, n)
+		}
+		fmt.Fprintf(w, "  unreachable: %d\n", h.Unreachable)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/network"
+)
+
+func exportGraph(args []string) {
+	fs := flag.NewFlagSet("export-graph", flag.ExitOnError)
+	var format = fs.String("format", network.GraphML, "format of the graph: "+strings.Join(network.GraphFormats, ", "))
+	var out = fs.String("out", "", "file to write the graph to instead of stdout")
+	c, err := network.NewConfig(fs, args)
+	if err == nil {
+		err = writeGraph(c, *format, *out)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var out = fs.String("out", "", "file to write the ndjson export to instead of stdout")
+	c, err := network.NewConfig(fs, args)
+	if err == nil {
+		err = writeExport(c, *out)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func writeExport(c *network.Config, out string) error {
+	return writeTo(out, c.ExportNodes)
+}
+
+// writeTo has write write to the file out, stdout if out is empty.
+// The error of closing the file is returned if writing succeeded, as
+// the file may be cut short otherwise.
+func writeTo(out string, write func(io.Writer) error) error {
+	if out == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeGraph(c *network.Config, format, out string) error {
+	if err := network.ValidGraphFormat(format); err != nil {
+		return err
+	}
+	return writeTo(out, func(w io.Writer) error {
+		return c.ExportGraph(w, format)
+	})
+}
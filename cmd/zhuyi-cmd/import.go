@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/kraem/zhuyi-go/importer"
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// ndjson is the format of zhuyi's own exports, see export
+const ndjson = "ndjson"
+
+func importNotes(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var from = fs.String("from", "", "format to import from: obsidian, logseq, roam or ndjson")
+	var src = fs.String("src", "", "vault or graph directory, json export for roam, or ndjson export (- for stdin)")
+	var conflict = fs.String("conflict", network.ConflictSkip, "what to do with existing nodes when importing ndjson: skip, overwrite or rename")
+	var dryRun = fs.Bool("dry-run", false, "report what would be imported without writing anything")
+	c, err := network.NewConfig(fs, args)
+	if *from == ndjson {
+		importExport(c, err, *src, *conflict, *dryRun)
+		return
+	}
+
+	var resp payloads.ImportReportResponse
+	resp.Payload.DryRun = *dryRun
+
+	var r *importer.Report
+	if err == nil {
+		r, err = runImport(c, *from, *src, *dryRun)
+	}
+	if err != nil {
+		errString := err.Error()
+		resp.Error = &errString
+		json.NewEncoder(os.Stderr).Encode(resp)
+		os.Exit(1)
+	}
+
+	resp.Payload.Report = r
+	json.NewEncoder(os.Stdout).Encode(resp)
+}
+
+func runImport(c *network.Config, from, src string, dryRun bool) (*importer.Report, error) {
+	s, err := importer.NewSource(from, src)
+	if err != nil {
+		return nil, err
+	}
+	return importer.Import(c, s, dryRun)
+}
+
+// importExport imports an ndjson export into c, err is
+// the error setting up c, if any
+func importExport(c *network.Config, err error, src, conflict string, dryRun bool) {
+	var resp payloads.ImportResponse
+	resp.Payload.DryRun = dryRun
+
+	var res *network.ImportResult
+	if err == nil {
+		res, err = runImportExport(c, src, conflict, dryRun)
+	}
+	if err != nil {
+		errString := err.Error()
+		resp.Error = &errString
+		json.NewEncoder(os.Stderr).Encode(resp)
+		os.Exit(1)
+	}
+
+	resp.Payload.Result = res
+	json.NewEncoder(os.Stdout).Encode(resp)
+}
+
+func runImportExport(c *network.Config, src, conflict string, dryRun bool) (*network.ImportResult, error) {
+	var r io.Reader = os.Stdin
+	if src != "-" && src != "" {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return c.ImportNodesFrom(r, conflict, dryRun)
+}
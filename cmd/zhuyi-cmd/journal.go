@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/kraem/zhuyi-go/network"
+)
+
+func journal(args []string) {
+	fs := flag.NewFlagSet("journal", flag.ExitOnError)
+	var date = fs.String("date", "today", "day of the journal node, 2006-01-02, today or yesterday")
+	var text = fs.String("text", "", "entry to add to the journal node, if any")
+	c, err := network.NewConfig(fs, args)
+	if err != nil {
+		writeOutput(nil, nil, err)
+		os.Exit(1)
+	}
+
+	day, err := network.ParseJournalDate(*date)
+	if err != nil {
+		writeOutput(nil, nil, err)
+		os.Exit(1)
+	}
+
+	var fileName string
+	var changed []string
+	if *text == "" {
+		var nc *network.NodeContent
+		if nc, err = c.Journal(day); err == nil {
+			fileName = nc.File
+		}
+	} else {
+		fileName, changed, err = c.AppendJournal(day, *text)
+	}
+	if err != nil {
+		writeOutput(nil, nil, err)
+		os.Exit(1)
+	}
+
+	writeOutput(&fileName, changed, nil)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [command] [flags]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "commands:\n")
+	fmt.Fprintf(os.Stderr, "  create  create a new node (default)\n")
+	fmt.Fprintf(os.Stderr, "  doctor  report structural problems in the network\n")
+	fmt.Fprintf(os.Stderr, "  journal open the journal node of a day, or add an entry to it\n")
+	fmt.Fprintf(os.Stderr, "  export  write every node as ndjson\n")
+	fmt.Fprintf(os.Stderr, "  import  import notes from obsidian, logseq, roam or an ndjson export\n")
+	fmt.Fprintf(os.Stderr, "  export-graph  write the graph as graphml, gexf or dot\n")
+}
+
+func main() {
+	cmd, args := "create", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "create":
+		create(args)
+	case "doctor":
+		doctor(args)
+	case "journal":
+		journal(args)
+	case "export":
+		export(args)
+	case "import":
+		importNotes(args)
+	case "export-graph":
+		exportGraph(args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -1,26 +1,47 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"flag"
+	"os"
 
-	"github.com/gorilla/mux"
+	"github.com/kraem/zhuyi-go/pkg/config"
+	"github.com/kraem/zhuyi-go/pkg/log"
 	"github.com/kraem/zhuyi-go/server"
 )
 
 func main() {
 
-	s := server.NewServer()
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "print the configuration and exit")
 
-	r := mux.NewRouter()
-	r.Handle("/status", server.StatusHandler(s)).Methods("GET")
-	r.Handle("/d3/graph", server.GraphHandler(s)).Methods("GET")
-	r.Handle("/unlinked", server.UnlinkedHandler(s)).Methods("GET")
-	r.Handle("/node/add", server.AddNodeHandler(s)).Methods("POST")
-	r.Handle("/node/del", server.DelNodeHandler(s)).Methods("POST")
-	// TODO Handle options like this for all endpoints
-	r.Handle("/node/add", server.AddNodeHandlerOptions(s)).Methods("OPTIONS")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		fatal(err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if err := log.Configure(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		fatal(err)
+	}
+	s, err := server.NewServer(cfg)
+	if err != nil {
+		fatal(err)
+	}
 
-	log.Fatal(http.ListenAndServe(s.Cfg.Addr, r))
+	r := server.NewRouter(s)
 
+	s.WarmUp()
+	if err := s.Serve(server.AccessLog(r, s.CORS.Handler(r))); err != nil {
+		fatal(err)
+	}
+
+}
+
+func fatal(err error) {
+	log.Error(err.Error())
+	os.Exit(1)
 }
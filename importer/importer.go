@@ -0,0 +1,397 @@
+// Package importer converts notes from other tools
+// (Obsidian, Logseq, Roam) into zhuyi nodes.
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kraem/zhuyi-go/network"
+)
+
+// Note is a note read from another tool.
+type Note struct {
+	// Key is the name other notes refer to the note by,
+	// e.g. the page name or the path in the vault
+	Key string
+	// Aliases are other names the note is referred by, e.g. the
+	// file name without the folders. Keys win over aliases and
+	// aliases shared by notes are left out.
+	Aliases []string
+	Title   string
+	Date    time.Time
+	Tags    []string
+	Body    string
+	// Dir is the directory of the note relative to the
+	// vault, relative markdown links are resolved from it
+	Dir string
+}
+
+// Block is a block of a note which can be referenced by its id,
+// ((id)), as Logseq and Roam do.
+type Block struct {
+	Key  string
+	Text string
+}
+
+// Source is a collection of notes in another tool's format
+type Source interface {
+	Read() ([]Note, map[string]Block, error)
+}
+
+// Imported maps a note to the node it became
+type Imported struct {
+	Key      string `json:"key"`
+	FileName string `json:"file_name"`
+}
+
+// Unresolved is a reference in a note to something which doesn't exist
+type Unresolved struct {
+	Key       string `json:"key"`
+	Reference string `json:"reference"`
+}
+
+// Report sums up an import
+type Report struct {
+	Imported   []Imported   `json:"imported"`
+	Unresolved []Unresolved `json:"unresolved"`
+}
+
+// NewSource returns the importer of the format, reading from path
+func NewSource(format, path string) (Source, error) {
+	switch format {
+	case "obsidian":
+		return Obsidian{Path: path}, nil
+	case "logseq":
+		return Logseq{Path: path}, nil
+	case "roam":
+		return Roam{Path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown import format: %v", format)
+}
+
+// [[page]], ![[page]], #[[page]], [[page#heading|alias]]
+var wikiLinkExtractor = regexp.MustCompile(`(!|#)?\[\[([^\[\]]+)\]\]`)
+
+// ((block-id))
+var blockRefExtractor = regexp.MustCompile(`\(\(([0-9A-Za-z_-]+)\)\)`)
+
+var mdLinkExtractor = regexp.MustCompile(`(!?)\[([^\[\]]*)\]\(([^)]*)\)`)
+
+// Import converts the notes of src into nodes of the network, rewriting
+// the references between them into markdown links. Nothing is written
+// when dryRun is set, the report then shows what would be imported.
+func Import(c *network.Config, src Source, dryRun bool) (*Report, error) {
+	notes, blocks, err := src.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]int, len(notes))
+	for i, n := range notes {
+		keys[strings.ToLower(n.Key)] = i
+	}
+	aliases := make(map[string]int)
+	for i, n := range notes {
+		for _, a := range n.Aliases {
+			a = strings.ToLower(a)
+			if _, exists := keys[a]; exists {
+				continue
+			}
+			if j, exists := aliases[a]; exists && j != i {
+				aliases[a] = -1
+				continue
+			}
+			aliases[a] = i
+		}
+	}
+	for a, i := range aliases {
+		if i >= 0 {
+			keys[a] = i
+		}
+	}
+
+	r := &Report{
+		Imported:   make([]Imported, 0, len(notes)),
+		Unresolved: make([]Unresolved, 0),
+	}
+
+	rewrite := func(i int, fileNames []string) string {
+		return rewriteRefs(notes[i], keys, blocks, fileNames, func(ref string) {
+			r.Unresolved = append(r.Unresolved, Unresolved{Key: notes[i].Key, Reference: ref})
+		})
+	}
+
+	var fileNames []string
+	if dryRun {
+		fileNames = make([]string, len(notes))
+		for i, n := range notes {
+			fileNames[i] = n.Key + ".md"
+			rewrite(i, fileNames)
+		}
+	} else {
+		ns := make([]network.ImportNode, 0, len(notes))
+		for _, n := range notes {
+			ns = append(ns, network.ImportNode{
+				NodeOptions: network.NodeOptions{
+					Title: n.Title,
+					Tags:  n.Tags,
+				},
+				Date: n.Date,
+			})
+		}
+		fileNames, err = c.ImportNodes(ns, rewrite)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, n := range notes {
+		r.Imported = append(r.Imported, Imported{Key: n.Key, FileName: fileNames[i]})
+	}
+	sort.Slice(r.Unresolved, func(i, j int) bool {
+		if r.Unresolved[i].Key != r.Unresolved[j].Key {
+			return r.Unresolved[i].Key < r.Unresolved[j].Key
+		}
+		return r.Unresolved[i].Reference < r.Unresolved[j].Reference
+	})
+
+	return r, nil
+}
+
+// rewriteRefs turns wiki links, block references and relative markdown
+// links to other notes into markdown links to the nodes they became.
+func rewriteRefs(n Note, keys map[string]int, blocks map[string]Block, fileNames []string, unresolved func(string)) string {
+	resolve := func(key string) (string, bool) {
+		i, exists := keys[strings.ToLower(strings.TrimSpace(key))]
+		if !exists {
+			return "", false
+		}
+		return fileNames[i], true
+	}
+
+	// markdown links go first, so the links the other
+	// references are rewritten into aren't resolved again
+	body := mdLinkExtractor.ReplaceAllStringFunc(n.Body, func(m string) string {
+		tokens := mdLinkExtractor.FindStringSubmatch(m)
+		target := tokens[3]
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+			return m
+		}
+		fragment := ""
+		if i := strings.Index(target, "#"); i >= 0 {
+			target, fragment = target[:i], target[i+1:]
+		}
+		if !strings.HasSuffix(target, ".md") {
+			return m
+		}
+		if u, err := url.PathUnescape(target); err == nil {
+			target = u
+		}
+		// relative to the note, or to the root of the vault
+		f, exists := resolve(strings.TrimSuffix(joinPath(n.Dir, target), ".md"))
+		if !exists {
+			f, exists = resolve(strings.TrimSuffix(joinPath("", target), ".md"))
+		}
+		if !exists {
+			unresolved(m)
+			return m
+		}
+		return tokens[1] + "[" + tokens[2] + "](" + f + fragmentSuffix(fragment) + ")"
+	})
+
+	body = wikiLinkExtractor.ReplaceAllStringFunc(body, func(m string) string {
+		tokens := wikiLinkExtractor.FindStringSubmatch(m)
+		prefix, inner := tokens[1], tokens[2]
+
+		target, alias := inner, ""
+		if i := strings.Index(target, "|"); i >= 0 {
+			target, alias = target[:i], target[i+1:]
+		}
+		fragment := ""
+		if i := strings.Index(target, "#"); i >= 0 {
+			target, fragment = target[:i], target[i+1:]
+			// links to obsidian blocks, ^id, become links to the note
+			if strings.HasPrefix(fragment, "^") {
+				fragment = ""
+			} else {
+				fragment = network.HeadingAnchor(fragment)
+			}
+		}
+		if alias == "" {
+			alias = target
+		}
+
+		f, exists := resolve(target)
+		if !exists {
+			unresolved(m)
+			return alias
+		}
+		link := "[" + alias + "](" + f + fragmentSuffix(fragment) + ")"
+		if prefix == "!" {
+			return "!" + link
+		}
+		return link
+	})
+
+	body = blockRefExtractor.ReplaceAllStringFunc(body, func(m string) string {
+		id := blockRefExtractor.FindStringSubmatch(m)[1]
+		b, exists := blocks[id]
+		if !exists {
+			unresolved(m)
+			return m
+		}
+		f, exists := resolve(b.Key)
+		if !exists {
+			unresolved(m)
+			return b.Text
+		}
+		return "[" + b.Text + "](" + f + ")"
+	})
+
+	return body
+}
+
+func fragmentSuffix(fragment string) string {
+	if fragment == "" {
+		return ""
+	}
+	return "#" + url.PathEscape(fragment)
+}
+
+// joinPath joins a path relative to dir, both relative to the vault
+func joinPath(dir, p string) string {
+	parts := make([]string, 0)
+	if dir != "" && dir != "." {
+		parts = strings.Split(dir, "/")
+	}
+	for _, s := range strings.Split(p, "/") {
+		switch s {
+		case "", ".":
+		case "..":
+			if len(parts) > 0 {
+				parts = parts[:len(parts)-1]
+			}
+		default:
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// splitFrontMatter returns the yaml front matter fields of a markdown
+// file, the fields read as lists, the tags, and what comes after them.
+// A list is a block list, a flow list, [a, b], or comma separated.
+func splitFrontMatter(content string) (map[string]string, map[string][]string, []string, string) {
+	fields := make(map[string]string)
+	lists := make(map[string][]string)
+	tags := make([]string, 0)
+
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return fields, lists, tags, content
+	}
+
+	// the key a block list is being read for
+	inList := ""
+	for i := 1; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], "\r")
+		if strings.TrimSpace(l) == "---" {
+			return fields, lists, tags, strings.TrimLeft(strings.Join(lines[i+1:], "\n"), "\n")
+		}
+		if inList != "" && strings.HasPrefix(strings.TrimSpace(l), "- ") {
+			item := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "- "))
+			lists[inList] = append(lists[inList], unquote(item))
+			if inList == "tags" || inList == "tag" {
+				tags = append(tags, splitTags(item)...)
+			}
+			continue
+		}
+		inList = ""
+
+		i := strings.Index(l, ":")
+		if i < 0 {
+			continue
+		}
+		k, v := strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])
+		fields[k] = strings.Trim(v, `"'`)
+		lists[k] = splitList(v)
+		if v == "" {
+			inList = k
+		}
+		if k == "tags" || k == "tag" {
+			tags = append(tags, splitTags(v)...)
+		}
+	}
+
+	// no closing delimiter, it wasn't front matter after all
+	return map[string]string{}, map[string][]string{}, []string{}, content
+}
+
+func splitTags(v string) []string {
+	v = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(v), "["), "]")
+	tags := make([]string, 0)
+	for _, t := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }) {
+		t = strings.Trim(strings.TrimSpace(t), `"'#`)
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// splitList splits a flow list, [a, "b, c"], or comma separated
+// values on the commas outside of quotes, unquoting the items
+func splitList(v string) []string {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		v = v[1 : len(v)-1]
+	}
+
+	items := make([]string, 0)
+	add := func(s string) {
+		if s = unquote(strings.TrimSpace(s)); s != "" {
+			items = append(items, s)
+		}
+	}
+	var quote rune
+	start := 0
+	for i, r := range v {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		// only items starting with one are quoted, it's isn't
+		case (r == '"' || r == '\'') && strings.TrimSpace(v[start:i]) == "":
+			quote = r
+		case r == ',':
+			add(v[start:i])
+			start = i + 1
+		}
+	}
+	add(v[start:])
+	return items
+}
+
+// unquote removes the quotes around a yaml string
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseDate parses the dates found in front matter
+func parseDate(s string) (time.Time, bool) {
+	for _, f := range []string{"2006-01-02 15:04", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(f, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Logseq reads a Logseq graph: the pages and journals folders of
+// markdown outlines, referring to each other with [[page name]]
+// and to blocks with ((block id)).
+type Logseq struct {
+	Path string
+}
+
+// key:: value
+var logseqPropertyExtractor = regexp.MustCompile(`^\s*(?:- )?([A-Za-z0-9_-]+):: ?(.*)$`)
+
+const logseqJournalFormat = "2006_01_02"
+
+func (l Logseq) Read() ([]Note, map[string]Block, error) {
+	notes := make([]Note, 0)
+	blocks := make(map[string]Block)
+
+	for _, dir := range []string{"pages", "journals"} {
+		files, err := ioutil.ReadDir(filepath.Join(l.Path, dir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(f.Name(), ".md") {
+				continue
+			}
+			b, err := ioutil.ReadFile(filepath.Join(l.Path, dir, f.Name()))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			name := strings.TrimSuffix(f.Name(), ".md")
+			n := Note{
+				Key:  logseqPageName(name),
+				Date: f.ModTime(),
+			}
+			if dir == "journals" {
+				if t, err := time.ParseInLocation(logseqJournalFormat, name, time.Local); err == nil {
+					n.Key = logseqJournalTitle(t)
+					n.Aliases = []string{t.Format("2006-01-02")}
+					n.Date = t
+				}
+			}
+			n.Title = n.Key
+
+			n.Body = l.parse(string(b), &n, blocks)
+			notes = append(notes, n)
+		}
+	}
+
+	return notes, blocks, nil
+}
+
+// parse reads the page properties and the ids of the blocks of the
+// page, returning the body of the page without them.
+func (l Logseq) parse(content string, n *Note, blocks map[string]Block) string {
+	lines := strings.Split(content, "\n")
+	body := make([]string, 0, len(lines))
+
+	pageProperties := true
+	for _, line := range lines {
+		tokens := logseqPropertyExtractor.FindStringSubmatch(line)
+		if tokens == nil {
+			if strings.TrimSpace(line) != "" {
+				pageProperties = false
+			}
+			body = append(body, line)
+			continue
+		}
+
+		k, v := strings.ToLower(tokens[1]), strings.TrimSpace(tokens[2])
+		switch {
+		case k == "id":
+			// the id of the block before it
+			text := ""
+			for i := len(body) - 1; i >= 0; i-- {
+				if t := strings.TrimSpace(body[i]); strings.HasPrefix(t, "- ") {
+					text = strings.TrimPrefix(t, "- ")
+					break
+				}
+			}
+			blocks[v] = Block{Key: n.Key, Text: text}
+		case pageProperties && k == "title":
+			n.Key, n.Title = v, v
+		case pageProperties && k == "alias":
+			for _, a := range strings.Split(v, ",") {
+				n.Aliases = append(n.Aliases, strings.Trim(strings.TrimSpace(a), "[]"))
+			}
+		case pageProperties && (k == "tags" || k == "tag"):
+			for _, t := range strings.Split(v, ",") {
+				n.Tags = append(n.Tags, strings.Trim(strings.TrimSpace(t), "[]#"))
+			}
+		default:
+			body = append(body, line)
+		}
+	}
+
+	return strings.Trim(strings.Join(body, "\n"), "\n") + "\n"
+}
+
+// logseqPageName turns a file name into the name of its page,
+// namespaces are stored as a___b or a%2Fb for a/b
+func logseqPageName(fileName string) string {
+	name := strings.Replace(fileName, "___", "/", -1)
+	if u, err := url.PathUnescape(name); err == nil {
+		name = u
+	}
+	return name
+}
+
+// logseqJournalTitle is the default Logseq journal title, e.g. Oct 19th, 2026
+func logseqJournalTitle(t time.Time) string {
+	d := t.Day()
+	suffix := "th"
+	if d < 11 || d > 13 {
+		switch d % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%s %d%s, %d", t.Format("Jan"), d, suffix, t.Year())
+}
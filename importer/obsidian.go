@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Obsidian reads an Obsidian vault: markdown files in nested folders
+// referring to each other with [[wikilinks]] by file name or path,
+// or with markdown links relative to the note or the vault.
+type Obsidian struct {
+	Path string
+}
+
+func (o Obsidian) Read() ([]Note, map[string]Block, error) {
+	notes := make([]Note, 0)
+
+	err := filepath.Walk(o.Path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// .obsidian, .trash and such
+		if info.IsDir() && p != o.Path && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(o.Path, p)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimSuffix(filepath.ToSlash(rel), ".md")
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		fields, lists, tags, body := splitFrontMatter(string(b))
+
+		n := Note{
+			Key:     key,
+			Aliases: append([]string{path.Base(key)}, lists["aliases"]...),
+			Title:   fields["title"],
+			Date:    info.ModTime(),
+			Tags:    tags,
+			Body:    body,
+			Dir:     path.Dir(key),
+		}
+		if n.Title == "" {
+			n.Title = path.Base(key)
+		}
+		for _, f := range []string{"date", "created"} {
+			if t, ok := parseDate(fields[f]); ok {
+				n.Date = t
+				break
+			}
+		}
+
+		notes = append(notes, n)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return notes, map[string]Block{}, nil
+}
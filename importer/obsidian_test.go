@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/config"
+)
+
+// vault are the notes of an Obsidian vault, by path
+var vault = map[string]string{
+	"block.md": `---
+aliases:
+  - Graph theory
+  - "Graphs, and such"
+  - 'networks'
+tags:
+  - math
+  - "#quoted"
+  - '#single'
+---
+block list`,
+	"flow.md": `---
+aliases: [Go tools, "vet, fmt and such", 'go build']
+tags: ["#go", tools]
+---
+flow list`,
+	"plain.md": `---
+aliases: First alias, second alias
+---
+comma separated`,
+	"single.md": `---
+aliases: It's one alias
+---
+one alias`,
+	"dir/none.md": `no front matter`,
+	"links.md":    `[[Graph theory]] [[go build]] [[second alias]] [[It's one alias]] [[Graph]]`,
+}
+
+func writeVault(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for p, content := range vault {
+		p = filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestObsidianAliasesAndTags(t *testing.T) {
+	notes, _, err := Obsidian{Path: writeVault(t)}.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"block":    {"block", "Graph theory", "Graphs, and such", "networks"},
+		"flow":     {"flow", "Go tools", "vet, fmt and such", "go build"},
+		"plain":    {"plain", "First alias", "second alias"},
+		"single":   {"single", "It's one alias"},
+		"dir/none": {"none"},
+		"links":    {"links"},
+	}
+	got := make(map[string][]string)
+	for _, n := range notes {
+		got[n.Key] = n.Aliases
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aliases = %q, want %q", got, want)
+	}
+
+	wantTags := map[string][]string{
+		"block": {"math", "quoted", "single"},
+		"flow":  {"go", "tools"},
+	}
+	for _, n := range notes {
+		if want, exists := wantTags[n.Key]; exists && !reflect.DeepEqual(n.Tags, want) {
+			t.Errorf("tags of %v = %q, want %q", n.Key, n.Tags, want)
+		}
+	}
+}
+
+func TestObsidianImportResolvesAliases(t *testing.T) {
+	c, err := network.NewConfigFrom(config.Network{
+		Name:        "test",
+		NetworkPath: t.TempDir(),
+		SelfLoops:   string(network.SelfLoopsFlag),
+	}, config.Cache{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Import(c, Obsidian{Path: writeVault(t)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unresolved := make([]string, 0)
+	for _, u := range r.Unresolved {
+		unresolved = append(unresolved, u.Reference)
+	}
+	sort.Strings(unresolved)
+	if want := []string{"[[Graph]]"}; !reflect.DeepEqual(unresolved, want) {
+		t.Errorf("unresolved = %q, want %q", unresolved, want)
+	}
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// Roam reads a Roam Research JSON export: a list of pages, each
+// with a tree of blocks, referring to pages with [[page]] and
+// #[[page]] and to blocks with ((uid)).
+type Roam struct {
+	Path string
+}
+
+type roamPage struct {
+	Title      string      `json:"title"`
+	CreateTime int64       `json:"create-time"`
+	EditTime   int64       `json:"edit-time"`
+	Children   []roamBlock `json:"children"`
+}
+
+type roamBlock struct {
+	String   string      `json:"string"`
+	UID      string      `json:"uid"`
+	Heading  int         `json:"heading"`
+	Children []roamBlock `json:"children"`
+}
+
+func (r Roam) Read() ([]Note, map[string]Block, error) {
+	f, err := os.Open(r.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var pages []roamPage
+	if err := json.NewDecoder(f).Decode(&pages); err != nil {
+		return nil, nil, err
+	}
+
+	notes := make([]Note, 0, len(pages))
+	blocks := make(map[string]Block)
+	for _, p := range pages {
+		n := Note{
+			Key:   p.Title,
+			Title: p.Title,
+			Date:  time.Now(),
+		}
+		for _, ms := range []int64{p.CreateTime, p.EditTime} {
+			if ms > 0 {
+				n.Date = time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+				break
+			}
+		}
+
+		var b strings.Builder
+		writeRoamBlocks(&b, p.Title, p.Children, 0, blocks)
+		n.Body = b.String()
+
+		notes = append(notes, n)
+	}
+
+	return notes, blocks, nil
+}
+
+// writeRoamBlocks writes the block tree as a nested markdown list
+func writeRoamBlocks(b *strings.Builder, page string, bs []roamBlock, depth int, blocks map[string]Block) {
+	indent := strings.Repeat("  ", depth)
+	for _, bl := range bs {
+		if bl.UID != "" {
+			blocks[bl.UID] = Block{Key: page, Text: bl.String}
+		}
+
+		lines := strings.Split(bl.String, "\n")
+		if depth == 0 && bl.Heading > 0 {
+			b.WriteString(strings.Repeat("#", bl.Heading) + " " + lines[0] + "\n")
+		} else {
+			b.WriteString(indent + "- " + lines[0] + "\n")
+		}
+		for _, l := range lines[1:] {
+			b.WriteString(indent + "  " + l + "\n")
+		}
+
+		writeRoamBlocks(b, page, bl.Children, depth+1, blocks)
+	}
+}
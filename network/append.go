@@ -0,0 +1,88 @@
+package network
+
+import (
+	"strings"
+	"time"
+)
+
+// AppendNode appends text to the end of an existing node, or of the
+// section under heading, as a list item stamped with the current time.
+// Appends to the same node are serialized.
+func (c *Config) AppendNode(fileName, heading, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errorf(ErrInvalid, "nothing to append")
+	}
+	entry := listEntry(time.Now().Format(timeFormatFm), text)
+	return c.appendToNode(fileName, heading, entry)
+}
+
+// listEntry formats text as a list item prefixed with stamp,
+// indenting any following lines so they stay in the item
+func listEntry(stamp, text string) []string {
+	ls := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	ls[0] = "- " + stamp + " " + ls[0]
+	for i := 1; i < len(ls); i++ {
+		if ls[i] != "" {
+			ls[i] = "  " + ls[i]
+		}
+	}
+	return ls
+}
+
+// appendToNode appends lines last in the section under heading,
+// or last in the node when heading is empty.
+func (c *Config) appendToNode(fileName, heading string, entry []string) error {
+	fp, err := c.notePath(fileName)
+	if err != nil {
+		return err
+	}
+
+	unlock := c.locks.lock(fileName)
+	defer unlock()
+
+	lines, err := readLines(fp)
+	if err != nil {
+		return nodeError(fileName, err)
+	}
+	lines, err = appendToSection(lines, heading, entry)
+	if err != nil {
+		return err
+	}
+	content := []byte(strings.Join(lines, "\n"))
+	if err := c.checkNoteSize(fileName, len(content)); err != nil {
+		return err
+	}
+	return writeFileAtomic(fp, content, 0644)
+}
+
+// appendToSection adds entry after the last non blank line of the section
+// under heading, above any nested headings, or of the whole body when
+// heading is empty. A blank line is kept between the entry and anything
+// before it which isn't part of a list.
+func appendToSection(lines []string, heading string, entry []string) ([]string, error) {
+	start, end, err := sectionBounds(lines, heading)
+	if err != nil {
+		return nil, err
+	}
+
+	at := end
+	for at > start && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+
+	insert := entry
+	if at == start && start > 0 || at > start && !isListItem(lines[at-1]) && !isIndented(lines[at-1]) {
+		insert = append([]string{""}, insert...)
+	}
+	if at == len(lines) {
+		// keep the trailing newline
+		insert = append(insert, "")
+	} else if heading != "" && at == end {
+		insert = append(insert, "")
+	}
+
+	res := make([]string, 0, len(lines)+len(insert))
+	res = append(res, lines[:at]...)
+	res = append(res, insert...)
+	return append(res, lines[at:]...), nil
+}
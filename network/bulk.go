@@ -0,0 +1,373 @@
+package network
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/pkg/fs"
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// Policies for nodes in an import which already exist in the network
+const (
+	// ConflictSkip keeps the node in the network
+	ConflictSkip = "skip"
+	// ConflictOverwrite replaces the node in the network
+	ConflictOverwrite = "overwrite"
+	// ConflictRename imports the node under a new name, note-2.md,
+	// rewriting the links to it from the other imported nodes
+	ConflictRename = "rename"
+)
+
+// maxExportLine is the longest line of an export ImportNodesFrom accepts
+const maxExportLine = 64 * 1024 * 1024
+
+// FrontMatterField is a line of front matter, key: value. Lines which
+// aren't key value pairs are kept as they are in Value, with no Key.
+type FrontMatterField struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+	// Sep is what separates the key from the value when it isn't
+	// ": ", or ":" for keys without a value, e.g. of "title: "
+	Sep string `json:"sep,omitempty"`
+}
+
+// ExportedNode is a node as a line of an NDJSON export. FrontMatter
+// keeps the order of the fields, and is null for nodes without front
+// matter, so nodes are imported exactly as they were exported. Links
+// are only informational, they're read from the body again on import.
+type ExportedNode struct {
+	File        string             `json:"file"`
+	FrontMatter []FrontMatterField `json:"front_matter"`
+	Body        string             `json:"body"`
+	Links       []string           `json:"links"`
+}
+
+// ImportResult tells what happened to every node of an import
+type ImportResult struct {
+	Created     []string          `json:"created"`
+	Overwritten []string          `json:"overwritten"`
+	Skipped     []string          `json:"skipped"`
+	Renamed     map[string]string `json:"renamed"`
+}
+
+// ValidConflictPolicy returns an error if policy isn't skip, overwrite or rename
+func ValidConflictPolicy(policy string) error {
+	switch policy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return nil
+	}
+	return errorf(ErrInvalid, "invalid conflict policy: %v, expected skip, overwrite or rename", policy)
+}
+
+// ExportNodes writes every node in the network to w, one
+// ExportedNode per line, sorted by file name.
+func (c *Config) ExportNodes(w io.Writer) error {
+	files, err := ioutil.ReadDir(c.NetworkPath)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), mdExtension) {
+			continue
+		}
+		n, err := c.exportNode(f.Name())
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) exportNode(fileName string) (*ExportedNode, error) {
+	fp, err := c.notePath(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readLines(fp)
+	if err != nil {
+		return nil, nodeError(fileName, err)
+	}
+	links, embeds, err := extractMarkdownLinks(fp)
+	if err != nil {
+		return nil, nodeError(fileName, err)
+	}
+
+	n := &ExportedNode{
+		File:  fileName,
+		Links: edgeTargets(buildEdges(fileName, links, embeds, c.SelfLoops)),
+	}
+	at := bodyStart(lines)
+	if at > 0 {
+		n.FrontMatter = make([]FrontMatterField, 0, at-2)
+		for _, l := range lines[1 : at-1] {
+			n.FrontMatter = append(n.FrontMatter, splitFrontMatterField(l))
+		}
+	}
+	n.Body = strings.Join(lines[at:], "\n")
+	return n, nil
+}
+
+func splitFrontMatterField(l string) FrontMatterField {
+	if i := strings.Index(l, ": "); i > 0 {
+		f := FrontMatterField{Key: l[:i], Value: l[i+2:]}
+		if f.Value == "" {
+			f.Sep = ": "
+		}
+		return f
+	}
+	if strings.HasSuffix(l, ":") && len(l) > 1 && !strings.ContainsAny(l, " \t") {
+		return FrontMatterField{Key: strings.TrimSuffix(l, ":")}
+	}
+	return FrontMatterField{Value: l}
+}
+
+// separator returns Sep, or else the one of a line without it
+func (f FrontMatterField) separator() string {
+	switch {
+	case f.Sep != "":
+		return f.Sep
+	case f.Value == "":
+		return ":"
+	}
+	return ": "
+}
+
+// content turns the node back into the file it was exported from
+func (n *ExportedNode) content() []byte {
+	if n.FrontMatter == nil {
+		return []byte(n.Body)
+	}
+	var b strings.Builder
+	b.WriteString(yamlFmDelim + "\n")
+	for _, f := range n.FrontMatter {
+		if f.Key != "" {
+			b.WriteString(f.Key + f.separator())
+		}
+		b.WriteString(f.Value + "\n")
+	}
+	b.WriteString(yamlFmDelim + "\n")
+	b.WriteString(n.Body)
+	return []byte(b.String())
+}
+
+// ImportNodesFrom recreates the nodes of an export read from r. Nodes
+// which already exist are handled according to the conflict policy.
+// Nothing is written when dryRun is set, the result then tells what
+// would have happened. If writing a node fails, the nodes created are
+// removed and those overwritten put back, so an import is only ever
+// partially applied if the process dies halfway.
+func (c *Config) ImportNodesFrom(r io.Reader, policy string, dryRun bool) (*ImportResult, error) {
+	if err := ValidConflictPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	ns, err := readExport(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range ns {
+		if _, err := c.notePath(n.File); err != nil {
+			return nil, err
+		}
+		if err := c.checkNoteSize(n.File, len(n.content())); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &ImportResult{
+		Created:     make([]string, 0),
+		Overwritten: make([]string, 0),
+		Skipped:     make([]string, 0),
+		Renamed:     make(map[string]string),
+	}
+
+	// reserve the names first, so the links to renamed
+	// nodes can be rewritten before anything is written
+	reserved := make([]string, 0)
+	// the content of the nodes overwritten so far
+	previous := make(map[string][]byte)
+	rollback := func() {
+		for f, b := range previous {
+			unlock := c.locks.lock(f)
+			if err := writeFileAtomic(c.NetworkPath+f, b, 0644); err != nil {
+				log.LogError(err)
+			}
+			unlock()
+		}
+		for _, f := range reserved {
+			if err := os.Remove(c.NetworkPath + f); err != nil {
+				log.LogError(err)
+			}
+		}
+	}
+
+	// the names a dry run would have reserved
+	taken := make(map[string]bool)
+	overwrite := make(map[string]bool)
+
+	write := make([]*ExportedNode, 0, len(ns))
+	for _, n := range ns {
+		f, created, err := c.reserveImport(n.File, policy, dryRun, taken)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		switch {
+		case f == "":
+			res.Skipped = append(res.Skipped, n.File)
+			continue
+		case f != n.File:
+			res.Renamed[n.File] = f
+		case created:
+			res.Created = append(res.Created, f)
+		default:
+			res.Overwritten = append(res.Overwritten, f)
+			overwrite[f] = true
+		}
+		if created && !dryRun {
+			reserved = append(reserved, f)
+		}
+		write = append(write, n)
+	}
+
+	if dryRun {
+		return res, nil
+	}
+
+	for _, n := range write {
+		fileName := n.File
+		if f, renamed := res.Renamed[fileName]; renamed {
+			fileName = f
+		}
+		content := rewriteLinkTargets(string(n.content()), res.Renamed)
+
+		unlock := c.locks.lock(fileName)
+		var err error
+		if overwrite[fileName] {
+			var b []byte
+			if b, err = ioutil.ReadFile(c.NetworkPath + fileName); err == nil {
+				previous[fileName] = b
+			}
+		}
+		if err == nil {
+			err = writeFileAtomic(c.NetworkPath+fileName, []byte(content), 0644)
+		}
+		unlock()
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+	}
+
+	c.nodesCreated(len(res.Created) + len(res.Renamed))
+	return res, nil
+}
+
+// readExport reads every node of an export, the last one winning
+// if a file name occurs more than once.
+func readExport(r io.Reader) ([]*ExportedNode, error) {
+	ns := make([]*ExportedNode, 0)
+	at := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxExportLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var n ExportedNode
+		if err := json.Unmarshal(scanner.Bytes(), &n); err != nil {
+			return nil, errorf(ErrInvalid, "invalid export on line %d: %v", line, err)
+		}
+		if i, exists := at[n.File]; exists {
+			ns[i] = &n
+			continue
+		}
+		at[n.File] = len(ns)
+		ns = append(ns, &n)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, errorf(ErrTooLarge, "line %d of the export is longer than %d bytes", line+1, maxExportLine)
+		}
+		return nil, err
+	}
+	return ns, nil
+}
+
+// reserveImport returns the name to import the node as, empty if it's
+// skipped, and whether it was created rather than already existing.
+func (c *Config) reserveImport(fileName, policy string, dryRun bool, taken map[string]bool) (string, bool, error) {
+	base := strings.TrimSuffix(fileName, mdExtension)
+	for attempt := 0; attempt < maxNameAttempts; attempt++ {
+		f := fileName
+		if attempt > 0 {
+			f = base + "-" + strconv.Itoa(attempt+1) + mdExtension
+		}
+
+		var err error
+		if dryRun {
+			var exists bool
+			exists, err = fs.PathExists(c.NetworkPath + f)
+			if err == nil && (exists || taken[f]) {
+				err = os.ErrExist
+			}
+			if err == nil {
+				taken[f] = true
+			}
+		} else {
+			var fd *os.File
+			fd, err = fs.CreateExclusive(c.NetworkPath+f, 0644)
+			if err == nil {
+				err = fd.Close()
+			}
+		}
+		if err == nil {
+			return f, true, nil
+		}
+		if !os.IsExist(err) {
+			return "", false, err
+		}
+
+		switch policy {
+		case ConflictSkip:
+			return "", false, nil
+		case ConflictOverwrite:
+			return f, false, nil
+		}
+	}
+	return "", false, errorf(ErrConflict, "couldn't find a free name for %v", fileName)
+}
+
+// rewriteLinkTargets points the links to renamed nodes to their new names
+func rewriteLinkTargets(content string, renamed map[string]string) string {
+	if len(renamed) == 0 {
+		return content
+	}
+	return linkExtractor.ReplaceAllStringFunc(content, func(m string) string {
+		tokens := linkExtractor.FindStringSubmatch(m)
+		target, fragment := parseLink(tokens[2])
+		f, exists := renamed[target]
+		if !exists {
+			return m
+		}
+		if fragment != "" {
+			f += "#" + fragment
+		}
+		return "[" + tokens[1] + "](" + f + ")"
+	})
+}
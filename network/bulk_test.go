@@ -0,0 +1,254 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeNodes writes the nodes, by file name, to the network
+func writeNodes(t *testing.T, c *Config, nodes map[string]string) {
+	t.Helper()
+	for f, content := range nodes {
+		if err := ioutil.WriteFile(c.NetworkPath+f, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readNodes returns the content of every file in the network
+func readNodes(t *testing.T, c *Config) map[string]string {
+	t.Helper()
+	nodes := make(map[string]string)
+	for _, f := range files(t, c) {
+		b, err := ioutil.ReadFile(c.NetworkPath + f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[f] = string(b)
+	}
+	return nodes
+}
+
+func export(t *testing.T, c *Config) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := c.ExportNodes(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+var exportedNodes = map[string]string{
+	// the keys are in no particular order
+	"a.md": `---
+tags: one, two
+title: A
+custom: value: with colon
+root:
+date: 2021-02-02 13:47
+---
+
+# A
+
+links to [b](b.md) and [a heading of b](b.md#heading)
+`,
+	"b.md": `no front matter, links to [a](a.md)
+
+## Heading
+`,
+	// the empty title of nodes created without one keeps its space
+	"c.md": "---\ntitle: \n  - not a key\n---\nembeds ![[a]]",
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := testNetwork(t)
+	writeNodes(t, src, exportedNodes)
+	exported := export(t, src)
+
+	dst := testNetwork(t)
+	res, err := dst.ImportNodesFrom(bytes.NewReader(exported), ConflictSkip, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.md", "b.md", "c.md"}; !reflect.DeepEqual(res.Created, want) {
+		t.Errorf("created = %v, want %v", res.Created, want)
+	}
+
+	if got := readNodes(t, dst); !reflect.DeepEqual(got, exportedNodes) {
+		t.Errorf("imported nodes differ from the exported ones:\n%q\nwant\n%q", got, exportedNodes)
+	}
+	if again := export(t, dst); !bytes.Equal(again, exported) {
+		t.Errorf("export of the import = %s, want %s", again, exported)
+	}
+}
+
+func TestExportFrontMatterOrder(t *testing.T) {
+	c := testNetwork(t)
+	writeNodes(t, c, exportedNodes)
+
+	n, err := c.exportNode("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0)
+	for _, f := range n.FrontMatter {
+		keys = append(keys, f.Key)
+	}
+	if want := []string{"tags", "title", "custom", "root", "date"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("front matter keys = %v, want %v", keys, want)
+	}
+	if v := n.FrontMatter[2].Value; v != "value: with colon" {
+		t.Errorf("custom = %q, want the value after the first colon", v)
+	}
+
+	n, err = c.exportNode("b.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.FrontMatter != nil {
+		t.Errorf("front matter of a node without any = %v, want nil", n.FrontMatter)
+	}
+	if want := []string{"a.md"}; !reflect.DeepEqual(n.Links, want) {
+		t.Errorf("links = %v, want %v", n.Links, want)
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	src := testNetwork(t)
+	writeNodes(t, src, exportedNodes)
+	exported := export(t, src)
+
+	const existing = "---\ntitle: already here\n---\n"
+
+	tests := []struct {
+		policy string
+		want   ImportResult
+		nodes  map[string]string
+	}{
+		{ConflictSkip,
+			ImportResult{Created: []string{"b.md", "c.md"}, Skipped: []string{"a.md"}},
+			map[string]string{
+				"a.md": existing,
+				"b.md": exportedNodes["b.md"],
+				"c.md": exportedNodes["c.md"],
+			}},
+		{ConflictOverwrite,
+			ImportResult{Created: []string{"b.md", "c.md"}, Overwritten: []string{"a.md"}},
+			exportedNodes},
+		{ConflictRename,
+			ImportResult{Created: []string{"b.md", "c.md"}, Renamed: map[string]string{"a.md": "a-2.md"}},
+			map[string]string{
+				"a.md":   existing,
+				"a-2.md": exportedNodes["a.md"],
+				// links to the renamed node follow it, fragments included
+				"b.md": strings.Replace(exportedNodes["b.md"], "(a.md)", "(a-2.md)", 1),
+				"c.md": exportedNodes["c.md"],
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			for _, dryRun := range []bool{true, false} {
+				dst := testNetwork(t)
+				writeNodes(t, dst, map[string]string{"a.md": existing})
+
+				res, err := dst.ImportNodesFrom(bytes.NewReader(exported), tt.policy, dryRun)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !sameResult(*res, tt.want) {
+					t.Errorf("dry run %v: result = %+v, want %+v", dryRun, *res, tt.want)
+				}
+
+				want := tt.nodes
+				if dryRun {
+					want = map[string]string{"a.md": existing}
+				}
+				if got := readNodes(t, dst); !reflect.DeepEqual(got, want) {
+					t.Errorf("dry run %v: nodes = %q, want %q", dryRun, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRenameRewritesLinks(t *testing.T) {
+	dst := testNetwork(t)
+	writeNodes(t, dst, map[string]string{"a.md": "a", "b.md": "b"})
+
+	export := `{"file":"a.md","front_matter":null,"body":"new a"}
+{"file":"b.md","front_matter":null,"body":"[a](a.md) [a's heading](a.md#heading) [c](c.md) [self](b.md)"}
+{"file":"c.md","front_matter":null,"body":"[b](b.md)"}
+`
+	res, err := dst.ImportNodesFrom(strings.NewReader(export), ConflictRename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"a.md": "a-2.md", "b.md": "b-2.md"}; !reflect.DeepEqual(res.Renamed, want) {
+		t.Errorf("renamed = %v, want %v", res.Renamed, want)
+	}
+
+	want := map[string]string{
+		"a.md":   "a",
+		"b.md":   "b",
+		"a-2.md": "new a",
+		"b-2.md": "[a](a-2.md) [a's heading](a-2.md#heading) [c](c.md) [self](b-2.md)",
+		"c.md":   "[b](b-2.md)",
+	}
+	if got := readNodes(t, dst); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+}
+
+func TestImportRollsBackFailedWrite(t *testing.T) {
+	before := map[string]string{"a.md": "old a", "b.md": "old b"}
+	dst := testNetwork(t)
+	writeNodes(t, dst, before)
+
+	failed := errors.New("disk full")
+	write := writeFileAtomic
+	swapWrites(t, func(path string, data []byte, perm os.FileMode) error {
+		if strings.HasSuffix(path, "/c.md") {
+			return failed
+		}
+		return write(path, data, perm)
+	})
+
+	export := `{"file":"a.md","front_matter":null,"body":"new a"}
+{"file":"b.md","front_matter":null,"body":"new b"}
+{"file":"c.md","front_matter":null,"body":"new c"}
+{"file":"d.md","front_matter":null,"body":"new d"}
+`
+	_, err := dst.ImportNodesFrom(strings.NewReader(export), ConflictOverwrite, false)
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want %v", err, failed)
+	}
+	if got := readNodes(t, dst); !reflect.DeepEqual(got, before) {
+		t.Errorf("nodes after the failed import = %q, want %q", got, before)
+	}
+}
+
+// sameResult compares results, nil and empty being the same
+func sameResult(a, b ImportResult) bool {
+	list := func(l []string) []string {
+		l = append([]string{}, l...)
+		sort.Strings(l)
+		return l
+	}
+	m := func(m map[string]string) map[string]string {
+		if m == nil {
+			return map[string]string{}
+		}
+		return m
+	}
+	return reflect.DeepEqual(list(a.Created), list(b.Created)) &&
+		reflect.DeepEqual(list(a.Overwritten), list(b.Overwritten)) &&
+		reflect.DeepEqual(list(a.Skipped), list(b.Skipped)) &&
+		reflect.DeepEqual(m(a.Renamed), m(b.Renamed))
+}
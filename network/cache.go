@@ -0,0 +1,73 @@
+package network
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// nodeCache keeps what was parsed from the node files, so building the
+// graph only reads the files which changed since. A file is taken to
+// be unchanged as long as its modification time and size are.
+type nodeCache struct {
+	mu       sync.Mutex
+	maxNodes int
+	entries  map[string]cachedNode
+}
+
+type cachedNode struct {
+	modTime time.Time
+	size    int64
+
+	fmFields map[string]string
+	links    []string
+	embeds   []string
+}
+
+// newNodeCache returns a cache of at most maxNodes nodes, 0 for no limit
+func newNodeCache(maxNodes int) *nodeCache {
+	return &nodeCache{
+		maxNodes: maxNodes,
+		entries:  make(map[string]cachedNode),
+	}
+}
+
+func (nc *nodeCache) get(f os.FileInfo) (cachedNode, bool) {
+	if nc == nil {
+		return cachedNode{}, false
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	e, exists := nc.entries[f.Name()]
+	if !exists || !e.modTime.Equal(f.ModTime()) || e.size != f.Size() {
+		return cachedNode{}, false
+	}
+	return e, true
+}
+
+func (nc *nodeCache) put(f os.FileInfo, e cachedNode) {
+	if nc == nil {
+		return
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if _, exists := nc.entries[f.Name()]; !exists && nc.maxNodes > 0 && len(nc.entries) >= nc.maxNodes {
+		return
+	}
+	e.modTime, e.size = f.ModTime(), f.Size()
+	nc.entries[f.Name()] = e
+}
+
+// retain drops the nodes whose files are gone
+func (nc *nodeCache) retain(files map[string]bool) {
+	if nc == nil {
+		return
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	for f := range nc.entries {
+		if !files[f] {
+			delete(nc.entries, f)
+		}
+	}
+}
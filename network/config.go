@@ -1,22 +1,88 @@
 package network
 
 import (
-	"github.com/kraem/zhuyi-go/pkg/env"
+	"flag"
+	"os"
+
+	"github.com/kraem/zhuyi-go/pkg/config"
 	"github.com/kraem/zhuyi-go/pkg/fs"
 )
 
-const NETWORK_PATH = "NETWORK_PATH"
-
 type Config struct {
+	// Name of the network, see config.Network
+	Name        string
 	NetworkPath string
+	// Roots are the nodes the network is walked from
+	Roots     []string
+	SelfLoops SelfLoopPolicy
+	Namer     Namer
+	// MaxNoteSize is the most bytes a node written by
+	// the network may have, 0 for no limit
+	MaxNoteSize int
+
+	locks fileLocks
+	cache *nodeCache
+	// ready is set once WarmUp has read every node
+	ready int32
+}
+
+// ZHUYI_NETWORK is the name of the network NewConfig sets up
+// when several are configured, the default network if unset
+const ZHUYI_NETWORK = "ZHUYI_NETWORK"
+
+// NewConfig sets up a network from the config file, the environment
+// and the flags of fs parsed from args, see config.Load. The network
+// is picked by -network or ZHUYI_NETWORK, the default one if neither
+// is set. A nil fs skips the flags.
+func NewConfig(fs *flag.FlagSet, args []string) (*Config, error) {
+	name := os.Getenv(ZHUYI_NETWORK)
+	if fs != nil {
+		fs.StringVar(&name, "network", name, "network to use when several are configured (env "+ZHUYI_NETWORK+")")
+	}
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	n, err := cfg.Network(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewConfigFrom(n, cfg.Cache)
 }
 
-func NewConfig() (*Config, error) {
-	cfg := &Config{
-		NetworkPath: fs.AppendTrailingSlash(env.GetEnvOrExit(NETWORK_PATH)),
+// NewConfigFrom sets up the network configured by n
+func NewConfigFrom(n config.Network, cache config.Cache) (*Config, error) {
+	c := &Config{
+		Name:        n.Name,
+		NetworkPath: fs.AppendTrailingSlash(n.NetworkPath),
+		Roots:       n.RootNotes,
+		SelfLoops:   SelfLoopPolicy(n.SelfLoops),
+		MaxNoteSize: n.MaxNoteSize,
+	}
+	switch c.SelfLoops {
+	case SelfLoopsKeep, SelfLoopsFlag, SelfLoopsDrop:
+	default:
+		return nil, errorf(ErrInvalid, "invalid self_loops: %v", c.SelfLoops)
 	}
-	if err := fs.HavePermissions(cfg.NetworkPath); err != nil {
+	namer, err := NewNamer(n.NamingStrategy)
+	if err != nil {
 		return nil, err
 	}
-	return cfg, nil
+	c.Namer = namer
+	if cache.Enabled {
+		c.cache = newNodeCache(cache.MaxNodes)
+	}
+	if err := fs.HavePermissions(c.NetworkPath); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkNoteSize returns ErrTooLarge if a node
+// of size bytes is bigger than MaxNoteSize
+func (c *Config) checkNoteSize(fileName string, size int) error {
+	if c.MaxNoteSize > 0 && size > c.MaxNoteSize {
+		return errorf(ErrTooLarge, "%v would be %d bytes, more than the %d allowed", fileName, size, c.MaxNoteSize)
+	}
+	return nil
 }
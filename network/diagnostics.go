@@ -0,0 +1,200 @@
+package network
+
+import (
+	"sort"
+)
+
+// Cycle is a group of nodes which all reach each other.
+// Path is one closed chain through the group, starting
+// and ending at the same node, e.g. a.md -> b.md -> a.md
+type Cycle struct {
+	Nodes []string `json:"nodes"`
+	Path  []string `json:"path"`
+}
+
+// DepthHistogram counts the nodes at every depth from a root node.
+// Depths[d] is the number of nodes at depth d.
+type DepthHistogram struct {
+	Root        string `json:"root"`
+	Depths      []int  `json:"depths"`
+	Unreachable int    `json:"unreachable"`
+}
+
+// DiagnosticsReport holds the structural findings about a network.
+type DiagnosticsReport struct {
+	Cycles         []Cycle          `json:"cycles"`
+	DeadEnds       []string         `json:"dead_ends"`
+	SelfLinkedOnly []string         `json:"self_linked_only"`
+	Histograms     []DepthHistogram `json:"depth_histograms"`
+}
+
+// Diagnostics finds circular chains of nodes, dead-end nodes which
+// don't link to any other node, nodes only linking to themselves
+// and how deep down from the roots the nodes are.
+func (c *Config) Diagnostics() (*DiagnosticsReport, error) {
+	ns, err := c.linksPerFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := c.rootNodes(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	adj := localAdjacency(ns)
+
+	d := &DiagnosticsReport{
+		Cycles:         findCycles(ns, adj),
+		DeadEnds:       make([]string, 0),
+		SelfLinkedOnly: make([]string, 0),
+		Histograms:     make([]DepthHistogram, 0, len(roots)),
+	}
+
+	for f := range ns {
+		others, self := 0, 0
+		for _, l := range adj[f] {
+			if l == f {
+				self++
+			} else {
+				others++
+			}
+		}
+		switch {
+		case others > 0:
+		case self > 0:
+			d.SelfLinkedOnly = append(d.SelfLinkedOnly, f)
+		default:
+			d.DeadEnds = append(d.DeadEnds, f)
+		}
+	}
+	sort.Strings(d.DeadEnds)
+	sort.Strings(d.SelfLinkedOnly)
+
+	for _, r := range roots {
+		depths := depthsFrom(adj, r)
+		h := DepthHistogram{
+			Root:        r,
+			Unreachable: len(ns) - len(depths),
+		}
+		for _, dd := range depths {
+			for len(h.Depths) <= dd {
+				h.Depths = append(h.Depths, 0)
+			}
+			h.Depths[dd]++
+		}
+		d.Histograms = append(d.Histograms, h)
+	}
+
+	return d, nil
+}
+
+// findCycles returns the strongly connected components of the graph
+// with more than one node (Tarjan's algorithm). Self loops on their
+// own aren't considered cycles.
+func findCycles(ns map[string]Node, adj map[string][]string) []Cycle {
+	files := make([]string, 0, len(ns))
+	for f := range ns {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	index := 0
+	indices := make(map[string]int, len(files))
+	lowlinks := make(map[string]int, len(files))
+	onStack := make(map[string]bool, len(files))
+	stack := make([]string, 0)
+	cycles := make([]Cycle, 0)
+
+	var strongConnect func(v string)
+	strongConnect = func(v string) {
+		indices[v] = index
+		lowlinks[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range adj[v] {
+			if _, visited := indices[w]; !visited {
+				strongConnect(w)
+				if lowlinks[w] < lowlinks[v] {
+					lowlinks[v] = lowlinks[w]
+				}
+			} else if onStack[w] && indices[w] < lowlinks[v] {
+				lowlinks[v] = indices[w]
+			}
+		}
+
+		if lowlinks[v] != indices[v] {
+			return
+		}
+
+		component := make([]string, 0)
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, Cycle{
+				Nodes: component,
+				Path:  cyclePath(component, adj),
+			})
+		}
+	}
+
+	for _, f := range files {
+		if _, visited := indices[f]; !visited {
+			strongConnect(f)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i].Nodes[0] < cycles[j].Nodes[0]
+	})
+	return cycles
+}
+
+// cyclePath finds the shortest chain from the first node of the
+// component back to itself, staying inside the component.
+func cyclePath(component []string, adj map[string][]string) []string {
+	in := make(map[string]bool, len(component))
+	for _, f := range component {
+		in[f] = true
+	}
+
+	start := component[0]
+	prev := make(map[string]string)
+	queue := []string{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, l := range adj[n] {
+			if !in[l] || l == n {
+				continue
+			}
+			if l == start {
+				path := []string{start}
+				for p := n; p != start; p = prev[p] {
+					path = append(path, p)
+				}
+				path = append(path, start)
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			if _, seen := prev[l]; seen {
+				continue
+			}
+			prev[l] = n
+			queue = append(queue, l)
+		}
+	}
+	return component
+}
@@ -0,0 +1,182 @@
+package network
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultEmbedDepth is how many levels of embedded nodes
+// are expanded when no depth is asked for.
+const DefaultEmbedDepth = 5
+
+// ![[note]], ![[note#heading]] and ![[note|alias]]
+var wikiEmbedExtractor = regexp.MustCompile(`!\[\[([^\[\]]+)\]\]`)
+
+// NodeContent is a node together with its front matter and body.
+type NodeContent struct {
+	Node
+	FrontMatter map[string]string `json:"front_matter"`
+	Body        string            `json:"body"`
+}
+
+// ReadNode returns the front matter and body of a node.
+func (c *Config) ReadNode(fileName string) (*NodeContent, error) {
+	fp, err := c.notePath(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := readLines(fp)
+	if err != nil {
+		return nil, nodeError(fileName, err)
+	}
+
+	fmFields, err := extractFrontMatterFields(fp)
+	if err != nil {
+		return nil, nodeError(fileName, err)
+	}
+
+	links, embeds, err := extractMarkdownLinks(fp)
+	if err != nil {
+		return nil, err
+	}
+	edges := buildEdges(fileName, links, embeds, c.SelfLoops)
+
+	return &NodeContent{
+		Node: Node{
+			Title: fmFields["title"],
+			File:  fileName,
+			Date:  fmFields["date"],
+			Root:  fmFields[yamlFmRootField] == "true",
+			Links: edgeTargets(edges),
+			Edges: edges,
+		},
+		FrontMatter: fmFields,
+		Body:        strings.Join(lines[bodyStart(lines):], "\n"),
+	}, nil
+}
+
+// ExpandNode reads a node and replaces every embed in its body with the
+// body of the node (or section of it) it embeds, recursively, down to
+// maxDepth levels. Embeds which would create a cycle, are too deep or
+// point to nodes which don't exist are left as they are.
+func (c *Config) ExpandNode(fileName string, maxDepth int) (*NodeContent, error) {
+	nc, err := c.ReadNode(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if maxDepth < 0 {
+		return nil, errorf(ErrInvalid, "invalid embed depth: %d", maxDepth)
+	}
+
+	expanding := map[string]bool{fileName: true}
+	nc.Body = c.expandEmbeds(nc.Body, expanding, maxDepth)
+
+	return nc, nil
+}
+
+func (c *Config) expandEmbeds(body string, expanding map[string]bool, depth int) string {
+	if depth == 0 {
+		return body
+	}
+
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		lines[i] = replaceEmbeds(l, func(target, fragment string) (string, bool) {
+			if expanding[target] {
+				return "", false
+			}
+			fp, err := c.notePath(target)
+			if err != nil {
+				return "", false
+			}
+			embedded, err := readLines(fp)
+			if err != nil {
+				return "", false
+			}
+
+			section := embedded[bodyStart(embedded):]
+			if fragment != "" {
+				s, exists := sectionLines(embedded, fragment)
+				if !exists {
+					return "", false
+				}
+				section = s
+			}
+
+			expanding[target] = true
+			defer delete(expanding, target)
+
+			content := strings.Trim(strings.Join(section, "\n"), "\n")
+			return c.expandEmbeds(content, expanding, depth-1), true
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceEmbeds calls expand for every embed on the line and replaces
+// the embed with what it returns, unless it returns false.
+func replaceEmbeds(line string, expand func(target, fragment string) (string, bool)) string {
+	type span struct {
+		start, end       int
+		target, fragment string
+	}
+	spans := make([]span, 0)
+
+	for _, loc := range wikiEmbedExtractor.FindAllStringSubmatchIndex(line, -1) {
+		target, fragment := parseWikiLink(line[loc[2]:loc[3]])
+		spans = append(spans, span{loc[0], loc[1], target, fragment})
+	}
+	for _, loc := range linkExtractor.FindAllStringSubmatchIndex(line, -1) {
+		if loc[0] == 0 || line[loc[0]-1] != '!' {
+			continue
+		}
+		target, fragment := parseLink(line[loc[4]:loc[5]])
+		if !strings.HasSuffix(target, mdExtension) {
+			// an image or such
+			continue
+		}
+		spans = append(spans, span{loc[0] - 1, loc[1], target, fragment})
+	}
+
+	if len(spans) == 0 {
+		return line
+	}
+
+	// the two kinds of embeds can't overlap, so
+	// replacing from the end keeps the offsets valid
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start > spans[j].start
+	})
+	for _, s := range spans {
+		if content, ok := expand(s.target, s.fragment); ok {
+			line = line[:s.start] + content + line[s.end:]
+		}
+	}
+	return line
+}
+
+// parseWikiLink returns the node file name and #fragment of
+// the inside of a wiki link, e.g. "note#heading|alias"
+func parseWikiLink(inner string) (target, fragment string) {
+	if i := strings.Index(inner, "|"); i >= 0 {
+		inner = inner[:i]
+	}
+	target, fragment = splitLinkTarget(strings.TrimSpace(inner))
+	if target != "" && !strings.HasSuffix(target, mdExtension) {
+		target = target + mdExtension
+	}
+	return target, fragment
+}
+
+// sectionLines returns the lines of the section starting at the heading
+// matching fragment, up until the next heading of the same or a
+// higher level.
+func sectionLines(lines []string, fragment string) ([]string, bool) {
+	s, exists := findSection(lines, fragment)
+	if !exists {
+		return nil, false
+	}
+	return lines[s[0]-1 : s[1]], true
+}
@@ -0,0 +1,53 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Kinds of errors the network returns, errors.Is(err, ErrNotFound)
+// tells which kind an error is. Errors of none of them are internal.
+var (
+	// ErrNotFound is returned for nodes, headings and such which don't exist
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is returned for arguments which don't make sense
+	ErrInvalid = errors.New("invalid")
+	// ErrConflict is returned when the state of the network doesn't
+	// allow what's asked for, e.g. there's no free file name left
+	ErrConflict = errors.New("conflict")
+	// ErrTooLarge is returned for nodes bigger than the network allows
+	ErrTooLarge = errors.New("too large")
+)
+
+// Error is an error of one of the kinds above
+type Error struct {
+	kind error
+	msg  string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+func errorf(kind error, format string, a ...interface{}) error {
+	return &Error{kind: kind, msg: fmt.Sprintf(format, a...)}
+}
+
+// nodeError turns an error of reading or removing the node fileName
+// into one without its path, which tells where the network is, and
+// into ErrNotFound if the node doesn't exist.
+func nodeError(fileName string, err error) error {
+	if os.IsNotExist(err) {
+		return errorf(ErrNotFound, "file doesn't exist: %v", fileName)
+	}
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return fmt.Errorf("%v: %v", fileName, pe.Err)
+	}
+	return err
+}
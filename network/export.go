@@ -0,0 +1,307 @@
+package network
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats the graph can be exported to, see ExportGraph
+const (
+	GraphML = "graphml"
+	GEXF    = "gexf"
+	DOT     = "dot"
+)
+
+// GraphFormats are the formats ExportGraph supports
+var GraphFormats = []string{GraphML, GEXF, DOT}
+
+// GraphContentType returns the media type of an exported graph
+func GraphContentType(format string) string {
+	switch format {
+	case GraphML:
+		return "application/graphml+xml"
+	case GEXF:
+		return "application/gexf+xml"
+	case DOT:
+		return "text/vnd.graphviz"
+	}
+	return "application/octet-stream"
+}
+
+// ValidGraphFormat returns an error if ExportGraph doesn't support format
+func ValidGraphFormat(format string) error {
+	for _, f := range GraphFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return errorf(ErrInvalid, "invalid graph format: %v, expected one of %v", format, strings.Join(GraphFormats, ", "))
+}
+
+// ExportGraph writes the graph of the nodes in the network, and the
+// links between them, to w as GraphML, GEXF (Gephi) or Graphviz DOT.
+// Nodes carry their title, date and tags, edges their weight and kind.
+// Links to anything but the nodes of the network are left out.
+func (c *Config) ExportGraph(w io.Writer, format string) error {
+	if err := ValidGraphFormat(format); err != nil {
+		return err
+	}
+	defer c.observeGraphBuild(format, time.Now())
+
+	ns, err := c.linksPerFilename()
+	if err != nil {
+		return err
+	}
+
+	nodes := make([]Node, 0, len(ns))
+	for _, n := range ns {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].File < nodes[j].File
+	})
+
+	switch format {
+	case GraphML:
+		return writeGraphML(w, nodes, ns)
+	case GEXF:
+		return writeGEXF(w, nodes, ns)
+	}
+	return writeDOT(w, nodes, ns)
+}
+
+// exportEdges returns the edges of n to other nodes of the network
+func exportEdges(n Node, ns map[string]Node) []Edge {
+	es := make([]Edge, 0, len(n.Edges))
+	for _, e := range n.Edges {
+		if _, exists := ns[e.Target]; exists {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+func writeGraphML(w io.Writer, nodes []Node, ns map[string]Node) error {
+	g := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "date", For: "node", Name: "date", Type: "string"},
+			{ID: "tags", For: "node", Name: "tags", Type: "string"},
+			{ID: "root", For: "node", Name: "root", Type: "boolean"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+			{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+			{ID: "self_loop", For: "edge", Name: "self_loop", Type: "boolean"},
+		},
+	}
+	g.Graph.ID = "zhuyi"
+	g.Graph.EdgeDefault = "directed"
+
+	for _, n := range nodes {
+		g.Graph.Nodes = append(g.Graph.Nodes, graphMLNode{
+			ID: n.File,
+			Data: []graphMLData{
+				{Key: "title", Value: n.Title},
+				{Key: "date", Value: n.Date},
+				{Key: "tags", Value: strings.Join(n.Tags, ",")},
+				{Key: "root", Value: strconv.FormatBool(n.Root)},
+			},
+		})
+		for _, e := range exportEdges(n, ns) {
+			g.Graph.Edges = append(g.Graph.Edges, graphMLEdge{
+				ID:     "e" + strconv.Itoa(len(g.Graph.Edges)),
+				Source: n.File,
+				Target: e.Target,
+				Data: []graphMLData{
+					{Key: "weight", Value: strconv.Itoa(e.Weight)},
+					{Key: "kind", Value: string(e.Kind)},
+					{Key: "self_loop", Value: strconv.FormatBool(e.SelfLoop)},
+				},
+			})
+		}
+	}
+
+	return writeXML(w, g)
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Weight    int            `xml:"weight,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexf struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+		Attributes      []gexfAttributes `xml:"attributes"`
+		Nodes           []gexfNode       `xml:"nodes>node"`
+		Edges           []gexfEdge       `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+func writeGEXF(w io.Writer, nodes []Node, ns map[string]Node) error {
+	g := gexf{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+	}
+	g.Graph.DefaultEdgeType = "directed"
+	g.Graph.Attributes = []gexfAttributes{
+		{
+			Class: "node",
+			Attributes: []gexfAttribute{
+				{ID: "date", Title: "date", Type: "string"},
+				{ID: "tags", Title: "tags", Type: "liststring"},
+				{ID: "root", Title: "root", Type: "boolean"},
+			},
+		},
+		{
+			Class: "edge",
+			Attributes: []gexfAttribute{
+				{ID: "kind", Title: "kind", Type: "string"},
+				{ID: "self_loop", Title: "self_loop", Type: "boolean"},
+			},
+		},
+	}
+
+	for _, n := range nodes {
+		g.Graph.Nodes = append(g.Graph.Nodes, gexfNode{
+			ID:    n.File,
+			Label: n.Title,
+			AttValues: []gexfAttValue{
+				{For: "date", Value: n.Date},
+				{For: "tags", Value: strings.Join(n.Tags, "|")},
+				{For: "root", Value: strconv.FormatBool(n.Root)},
+			},
+		})
+		for _, e := range exportEdges(n, ns) {
+			g.Graph.Edges = append(g.Graph.Edges, gexfEdge{
+				ID:     strconv.Itoa(len(g.Graph.Edges)),
+				Source: n.File,
+				Target: e.Target,
+				Weight: e.Weight,
+				Label:  string(e.Kind),
+				AttValues: []gexfAttValue{
+					{For: "kind", Value: string(e.Kind)},
+					{For: "self_loop", Value: strconv.FormatBool(e.SelfLoop)},
+				},
+			})
+		}
+	}
+
+	return writeXML(w, g)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeDOT(w io.Writer, nodes []Node, ns map[string]Node) error {
+	var b strings.Builder
+	b.WriteString("digraph zhuyi {\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, date=%s, tags=%s, root=%t];\n",
+			dotQuote(n.File), dotQuote(n.Title), dotQuote(n.Date), dotQuote(strings.Join(n.Tags, ",")), n.Root)
+	}
+	for _, n := range nodes {
+		for _, e := range exportEdges(n, ns) {
+			fmt.Fprintf(&b, "  %s -> %s [weight=%d, kind=%s", dotQuote(n.File), dotQuote(e.Target), e.Weight, e.Kind)
+			if e.Kind == EdgeEmbed {
+				b.WriteString(", style=dashed")
+			}
+			if e.SelfLoop {
+				b.WriteString(", self_loop=true")
+			}
+			b.WriteString("];\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes s as a DOT id
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
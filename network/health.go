@@ -0,0 +1,46 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+)
+
+// healthProbe is the prefix of the files CheckWritable writes. They
+// aren't nodes, so they don't show up in the network even if one
+// is left behind.
+const healthProbe = ".healthz-"
+
+// WarmUp reads every node of the network, filling the cache when it's
+// enabled, after which the network is Ready. It's meant to be run in
+// the background when the network is set up, so the first requests
+// don't pay for parsing the whole network.
+func (c *Config) WarmUp() error {
+	if _, err := c.linksPerFilename(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.ready, 1)
+	return nil
+}
+
+// Ready tells if WarmUp has finished
+func (c *Config) Ready() bool {
+	return atomic.LoadInt32(&c.ready) == 1
+}
+
+// CheckWritable writes and removes a file in the network, catching
+// read only mounts and full disks which checking permissions doesn't.
+func (c *Config) CheckWritable() error {
+	f, err := ioutil.TempFile(c.NetworkPath, healthProbe)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte("ok\n"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
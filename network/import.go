@@ -0,0 +1,58 @@
+package network
+
+import (
+	"os"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// ImportNode is a node from outside of the network, e.g. another tool,
+// created at Date. Its Body is left empty, see ImportNodes.
+type ImportNode struct {
+	NodeOptions
+	Date time.Time
+}
+
+// ImportNodes adds nodes to the network. File names are reserved for
+// all of them up front and passed to body, which returns the body of
+// node i with its references rewritten to point to them. Either all
+// nodes are created or none of them.
+func (c *Config) ImportNodes(ns []ImportNode, body func(i int, fileNames []string) string) ([]string, error) {
+	fileNames := make([]string, 0, len(ns))
+	rollback := func() {
+		for _, f := range fileNames {
+			if err := os.Remove(c.NetworkPath + f); err != nil {
+				log.LogError(err)
+			}
+		}
+	}
+
+	for _, n := range ns {
+		f, err := c.reserveNode(n.Title, n.Date)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		fileNames = append(fileNames, f)
+	}
+
+	for i, n := range ns {
+		o := n.NodeOptions
+		o.Body = body(i, fileNames)
+		content, err := c.renderNode(o, n.Date)
+		if err == nil {
+			err = c.checkNoteSize(fileNames[i], len(content))
+		}
+		if err == nil {
+			err = writeFileAtomic(c.NetworkPath+fileNames[i], content, 0644)
+		}
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+	}
+
+	c.nodesCreated(len(fileNames))
+	return fileNames, nil
+}
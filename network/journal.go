@@ -0,0 +1,316 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/fs"
+)
+
+// The journal is made up of a node per day, journal-2006-01-02.md,
+// linking to the days before and after it and to the index node of
+// its month, journal-2006-01.md. The month nodes are linked from
+// journal.md, which is a root node, so no day is ever unlinked.
+const journalPrefix = "journal"
+const journalDayFormat = "2006-01-02"
+const journalMonthFormat = "2006-01"
+const journalEntryFormat = "15:04"
+
+// journalNavMarker marks the line linking a day to its neighbours
+const journalNavMarker = "<!-- journal-nav -->"
+
+// journalLock serializes changes to the journal as a whole. It's no
+// file name, as the nodes of the journal are locked on their own while
+// it's held, see fileLocks.
+const journalLock = journalPrefix + "/"
+
+var journalDayExtractor = regexp.MustCompile(`^` + journalPrefix + `-(\d{4}-\d{2}-\d{2})` + mdExtension + `$`)
+
+// ParseJournalDate parses a day as 2006-01-02, "today" or "yesterday"
+func ParseJournalDate(s string) (time.Time, error) {
+	now := time.Now()
+	switch s {
+	case "", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+	t, err := time.ParseInLocation(journalDayFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}, errorf(ErrInvalid, "invalid journal date: %v", s)
+	}
+	return t, nil
+}
+
+func journalDayFile(day time.Time) string {
+	return journalPrefix + "-" + day.Format(journalDayFormat) + mdExtension
+}
+
+func journalMonthFile(day time.Time) string {
+	return journalPrefix + "-" + day.Format(journalMonthFormat) + mdExtension
+}
+
+// Journal opens the journal node of the day, creating it if needed.
+func (c *Config) Journal(day time.Time) (*NodeContent, error) {
+	fileName, _, err := c.ensureJournal(day)
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadNode(fileName)
+}
+
+// AppendJournal appends a timestamped entry to the journal node of the
+// day, creating it if needed, and returns the file name of the day and
+// every file changed.
+func (c *Config) AppendJournal(day time.Time, text string) (string, []string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", nil, errorf(ErrInvalid, "empty journal entry")
+	}
+
+	fileName, changed, err := c.ensureJournal(day)
+	if err != nil {
+		return "", nil, err
+	}
+
+	entry := listEntry(time.Now().Format(journalEntryFormat), text)
+	if err := c.appendToNode(fileName, "", entry); err != nil {
+		return "", nil, err
+	}
+
+	if len(changed) == 0 {
+		changed = []string{fileName}
+	}
+	return fileName, changed, nil
+}
+
+// ensureJournal creates the day, and the month and journal nodes linking
+// to it, if they don't exist yet. It returns the file name of the day and
+// the files it changed.
+func (c *Config) ensureJournal(day time.Time) (string, []string, error) {
+	fileName := journalDayFile(day)
+
+	unlock := c.locks.lock(journalLock)
+	defer unlock()
+
+	exist, err := fs.PathExists(c.NetworkPath + fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	if exist {
+		return fileName, nil, nil
+	}
+
+	changed := make([]string, 0)
+
+	monthFile := journalMonthFile(day)
+	ch, err := c.ensureJournalLink(journalPrefix+mdExtension, journalPrefix, time.Now(), true, monthFile, day.Format(journalMonthFormat))
+	if err != nil {
+		return "", nil, err
+	}
+	changed = append(changed, ch...)
+
+	month := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	ch, err = c.ensureJournalLink(monthFile, day.Format(journalMonthFormat), month, false, fileName, day.Format(journalDayFormat))
+	if err != nil {
+		return "", nil, err
+	}
+	changed = append(changed, ch...)
+
+	content := journalFrontMatter(day.Format(journalDayFormat), day, false)
+	unlockDay := c.locks.lock(fileName)
+	err = writeNewFile(c.NetworkPath+fileName, []byte(content))
+	unlockDay()
+	if err != nil && !os.IsExist(err) {
+		return "", nil, err
+	}
+	if err == nil {
+		c.nodesCreated(1)
+	}
+	changed = append(changed, fileName)
+
+	ch, err = c.relinkJournalDays(fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, f := range ch {
+		if f != fileName {
+			changed = append(changed, f)
+		}
+	}
+
+	return fileName, changed, nil
+}
+
+// ensureJournalLink creates the index node fileName, dated date, if it
+// doesn't exist and adds a link to target in it, keeping the list of
+// links sorted.
+func (c *Config) ensureJournalLink(fileName, title string, date time.Time, root bool, target, targetTitle string) ([]string, error) {
+	fp := c.NetworkPath + fileName
+
+	unlock := c.locks.lock(fileName)
+	defer unlock()
+
+	lines, err := readLines(fp)
+	created := os.IsNotExist(err)
+	if created {
+		lines = strings.Split(journalFrontMatter(title, date, root), "\n")
+	} else if err != nil {
+		return nil, nodeError(fileName, err)
+	}
+
+	for _, l := range lines {
+		if strings.Contains(l, "]("+target+")") {
+			return nil, nil
+		}
+	}
+
+	lines = insertSortedLink(lines, "["+targetTitle+"]("+target+")", target)
+	if err := writeFileAtomic(fp, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return nil, err
+	}
+	if created {
+		c.nodesCreated(1)
+	}
+	return []string{fileName}, nil
+}
+
+// relinkJournalDays rewrites the navigation of the day and the
+// days before and after it, returning the files changed.
+func (c *Config) relinkJournalDays(fileName string) ([]string, error) {
+	days, err := c.journalDays()
+	if err != nil {
+		return nil, err
+	}
+
+	i := sort.SearchStrings(days, fileName)
+	if i == len(days) || days[i] != fileName {
+		return nil, errorf(ErrNotFound, "journal day doesn't exist: %v", fileName)
+	}
+
+	changed := make([]string, 0, 3)
+	for j := i - 1; j <= i+1; j++ {
+		if j < 0 || j >= len(days) {
+			continue
+		}
+		prev, next := "", ""
+		if j > 0 {
+			prev = days[j-1]
+		}
+		if j < len(days)-1 {
+			next = days[j+1]
+		}
+
+		if err := c.setJournalNavOf(days[j], journalNav(days[j], prev, next)); err != nil {
+			return nil, err
+		}
+		changed = append(changed, days[j])
+	}
+	return changed, nil
+}
+
+// setJournalNavOf sets the navigation of the day fileName
+func (c *Config) setJournalNavOf(fileName, nav string) error {
+	fp := c.NetworkPath + fileName
+
+	unlock := c.locks.lock(fileName)
+	defer unlock()
+
+	lines, err := readLines(fp)
+	if err != nil {
+		return nodeError(fileName, err)
+	}
+	lines = setJournalNav(lines, nav)
+	return writeFileAtomic(fp, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// journalDays returns the file names of all days, oldest first
+func (c *Config) journalDays() ([]string, error) {
+	files, err := ioutil.ReadDir(c.NetworkPath)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]string, 0)
+	for _, f := range files {
+		if !f.IsDir() && journalDayExtractor.MatchString(f.Name()) {
+			days = append(days, f.Name())
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+func journalFrontMatter(title string, date time.Time, root bool) string {
+	fm := yamlFmDelim + "\n" +
+		yamlFmTitleField + title + "\n" +
+		yamlFmDateField + date.Format(timeFormatFm) + "\n"
+	if root {
+		fm += yamlFmRootField + ": true\n"
+	}
+	return fm + yamlFmDelim + "\n"
+}
+
+func journalNav(day, prev, next string) string {
+	title := func(f string) string {
+		return journalDayExtractor.FindStringSubmatch(f)[1]
+	}
+
+	t, _ := time.Parse(journalDayFormat, title(day))
+	parts := make([]string, 0, 3)
+	if prev != "" {
+		parts = append(parts, "[« "+title(prev)+"]("+prev+")")
+	}
+	parts = append(parts, "["+t.Format(journalMonthFormat)+"]("+journalMonthFile(t)+")")
+	if next != "" {
+		parts = append(parts, "["+title(next)+" »]("+next+")")
+	}
+	return strings.Join(parts, " · ") + " " + journalNavMarker
+}
+
+// setJournalNav replaces the navigation line, or adds
+// it first in the body if there isn't one yet
+func setJournalNav(lines []string, nav string) []string {
+	for i, l := range lines {
+		if strings.HasSuffix(l, journalNavMarker) {
+			lines[i] = nav
+			return lines
+		}
+	}
+	at := bodyStart(lines)
+	res := make([]string, 0, len(lines)+2)
+	res = append(res, lines[:at]...)
+	res = append(res, "", nav)
+	return append(res, lines[at:]...)
+}
+
+// insertSortedLink adds `- link` to the list of links to journal nodes,
+// before the first one sorting after target.
+func insertSortedLink(lines []string, link, target string) []string {
+	at := -1
+	for i := bodyStart(lines); i < len(lines); i++ {
+		if !isListItem(lines[i]) {
+			continue
+		}
+		tokens := linkExtractor.FindStringSubmatch(lines[i])
+		if tokens == nil || !strings.HasPrefix(tokens[2], journalPrefix) {
+			continue
+		}
+		if tokens[2] > target {
+			at = i
+			break
+		}
+		at = i + 1
+	}
+	if at < 0 {
+		res, _ := insertListItem(lines, link, "", PositionLast)
+		return res
+	}
+
+	res := make([]string, 0, len(lines)+1)
+	res = append(res, lines[:at]...)
+	res = append(res, "- "+link)
+	return append(res, lines[at:]...)
+}
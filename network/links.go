@@ -0,0 +1,149 @@
+package network
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// SelfLoopPolicy decides what the graph builder
+// does with links from a node to itself.
+type SelfLoopPolicy string
+
+const (
+	// SelfLoopsKeep keeps self loops as any other edge
+	SelfLoopsKeep SelfLoopPolicy = "keep"
+	// SelfLoopsFlag keeps self loops and marks them as such
+	SelfLoopsFlag SelfLoopPolicy = "flag"
+	// SelfLoopsDrop leaves self loops out of the graph
+	SelfLoopsDrop SelfLoopPolicy = "drop"
+)
+
+// EdgeKind tells links to a node apart from embeds of it.
+type EdgeKind string
+
+const (
+	EdgeLink  EdgeKind = "link"
+	EdgeEmbed EdgeKind = "embed"
+)
+
+// Edge is a link from a node to a node (or url). Duplicate links
+// of the same kind to the same target are collapsed into one
+// edge with a weight.
+type Edge struct {
+	Target   string   `json:"target"`
+	Kind     EdgeKind `json:"kind"`
+	Weight   int      `json:"weight"`
+	SelfLoop bool     `json:"self_loop,omitempty"`
+}
+
+// parseLink splits the target of a markdown link into what it points
+// to and its #fragment, if any. Local targets are url decoded and
+// stripped of query strings and leading `./`, so that `./a%20b.md?x#y`
+// resolves to the same node as `a b.md`.
+func parseLink(raw string) (target, fragment string) {
+	l := strings.TrimSpace(raw)
+
+	// [x](<a b.md>) and [x](a.md "title")
+	if strings.HasPrefix(l, "<") {
+		if i := strings.Index(l, ">"); i > 0 {
+			l = l[1:i]
+		}
+	} else if i := strings.IndexAny(l, " \t"); i >= 0 {
+		l = l[:i]
+	}
+
+	return splitLinkTarget(l)
+}
+
+// splitLinkTarget does the work of parseLink
+// once the link title has been stripped.
+func splitLinkTarget(l string) (target, fragment string) {
+	if isExternalLink(l) {
+		return l, ""
+	}
+
+	if i := strings.Index(l, "#"); i >= 0 {
+		l, fragment = l[:i], l[i+1:]
+	}
+	if i := strings.Index(l, "?"); i >= 0 {
+		l = l[:i]
+	}
+
+	if u, err := url.PathUnescape(l); err == nil {
+		l = u
+	}
+	if f, err := url.PathUnescape(fragment); err == nil {
+		fragment = f
+	}
+
+	for strings.HasPrefix(l, "./") {
+		l = l[2:]
+	}
+	if l != "" {
+		l = path.Clean(l)
+	}
+
+	return l, fragment
+}
+
+func isExternalLink(l string) bool {
+	return strings.Contains(l, "://") || strings.HasPrefix(l, "mailto:")
+}
+
+// buildEdges normalizes the raw links and embed targets of the node
+// file and collapses duplicates into weighted edges, in order of
+// appearance.
+func buildEdges(file string, rawLinks, embeds []string, selfLoops SelfLoopPolicy) []Edge {
+	edges := make([]Edge, 0, len(rawLinks)+len(embeds))
+	pos := make(map[Edge]int, len(rawLinks)+len(embeds))
+
+	add := func(target string, kind EdgeKind) {
+		if target == "" {
+			// a link to a heading in the same node
+			return
+		}
+
+		self := target == file
+		if self && selfLoops == SelfLoopsDrop {
+			return
+		}
+
+		key := Edge{Target: target, Kind: kind}
+		if i, exists := pos[key]; exists {
+			edges[i].Weight++
+			return
+		}
+
+		pos[key] = len(edges)
+		edges = append(edges, Edge{
+			Target:   target,
+			Kind:     kind,
+			Weight:   1,
+			SelfLoop: self && selfLoops == SelfLoopsFlag,
+		})
+	}
+
+	for _, raw := range rawLinks {
+		target, _ := parseLink(raw)
+		add(target, EdgeLink)
+	}
+	for _, target := range embeds {
+		add(target, EdgeEmbed)
+	}
+
+	return edges
+}
+
+// edgeTargets returns every target linked or embedded, once
+func edgeTargets(edges []Edge) []string {
+	targets := make([]string, 0, len(edges))
+	seen := make(map[string]bool, len(edges))
+	for _, e := range edges {
+		if !seen[e.Target] {
+			targets = append(targets, e.Target)
+			seen[e.Target] = true
+		}
+	}
+	return targets
+}
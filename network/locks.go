@@ -0,0 +1,45 @@
+package network
+
+import (
+	"sync"
+)
+
+// fileLocks serializes writes to the same node within this process.
+// The zero value is ready to use.
+type fileLocks struct {
+	mu    sync.Mutex
+	locks map[string]*fileLock
+}
+
+type fileLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the node file name and returns the function unlocking it
+func (l *fileLocks) lock(fileName string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*fileLock)
+	}
+	fl, exists := l.locks[fileName]
+	if !exists {
+		fl = &fileLock{}
+		l.locks[fileName] = fl
+	}
+	fl.refs++
+	l.mu.Unlock()
+
+	fl.Lock()
+
+	return func() {
+		fl.Unlock()
+
+		l.mu.Lock()
+		fl.refs--
+		if fl.refs == 0 {
+			delete(l.locks, fileName)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,258 @@
+package network
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// Mention is an occurrence of another node's title in the body
+// of a node which isn't already part of a markdown link.
+type Mention struct {
+	Target string `json:"target"`
+	Title  string `json:"title"`
+	Text   string `json:"text"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// NodeMentions holds all unlinked mentions found in a single node.
+type NodeMentions struct {
+	File     string    `json:"file"`
+	Title    string    `json:"title"`
+	Mentions []Mention `json:"mentions"`
+}
+
+// Spans of a line which are no mentions, next to links and embeds
+var (
+	wikiLinkExtractor   = regexp.MustCompile(`\[\[[^\[\]]+\]\]`)
+	inlineCodeExtractor = regexp.MustCompile("``.*?``|`[^`]*`")
+	urlExtractor        = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S+`)
+)
+
+type titleMatcher struct {
+	file  string
+	title string
+	re    *regexp.Regexp
+}
+
+// UnlinkedMentions scans the body of every node for titles of other
+// nodes (case-insensitive, word-bounded) which aren't already linked.
+func (c *Config) UnlinkedMentions() ([]NodeMentions, error) {
+	ns, err := c.linksPerFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	matchers := titleMatchers(ns)
+
+	res := make([]NodeMentions, 0)
+	for f, n := range ns {
+		lines, err := readLines(filepath.Join(c.NetworkPath, f))
+		if err != nil {
+			log.LogError(err)
+			continue
+		}
+
+		ms := lineMentions(lines, f, matchers)
+		if len(ms) == 0 {
+			continue
+		}
+
+		res = append(res, NodeMentions{
+			File:     f,
+			Title:    n.Title,
+			Mentions: ms,
+		})
+	}
+
+	// file names are the dates..
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].File > res[j].File
+	})
+
+	return res, nil
+}
+
+// LinkMention converts the unlinked mention of target found at line and
+// column in fileName into a markdown link, in place.
+func (c *Config) LinkMention(fileName, target string, line, column int) error {
+	fp, err := c.notePath(fileName)
+	if err != nil {
+		return err
+	}
+	tp, err := c.notePath(target)
+	if err != nil {
+		return err
+	}
+
+	fmFields, err := extractFrontMatterFields(tp)
+	if err != nil {
+		return nodeError(target, err)
+	}
+	matchers := titleMatchers(map[string]Node{
+		target: {Title: fmFields["title"], File: target},
+	})
+	if len(matchers) == 0 {
+		return errorf(ErrInvalid, "node has no title: %v", target)
+	}
+
+	unlock := c.locks.lock(fileName)
+	defer unlock()
+
+	lines, err := readLines(fp)
+	if err != nil {
+		return nodeError(fileName, err)
+	}
+	if line <= bodyStart(lines) || line > len(lines) {
+		return errorf(ErrInvalid, "line out of range: %d", line)
+	}
+	if fencedLines(lines)[line-1] {
+		return errorf(ErrConflict, "no unlinked mention of %v at %d:%d in %v", target, line, column, fileName)
+	}
+
+	l := lines[line-1]
+	for _, m := range findMentions(l, line, fileName, matchers) {
+		if m.Column != column {
+			continue
+		}
+		s := m.Column - 1
+		e := s + len(m.Text)
+		lines[line-1] = l[:s] + "[" + m.Text + "](" + target + ")" + l[e:]
+		return writeLines(fp, lines)
+	}
+
+	return errorf(ErrConflict, "no unlinked mention of %v at %d:%d in %v", target, line, column, fileName)
+}
+
+// titleMatchers returns case-insensitive matchers for the titles of ns,
+// longest title first so that longer titles win over titles they contain.
+func titleMatchers(ns map[string]Node) []titleMatcher {
+	ms := make([]titleMatcher, 0, len(ns))
+	for f, n := range ns {
+		t := strings.TrimSpace(n.Title)
+		if t == "" {
+			continue
+		}
+		ms = append(ms, titleMatcher{
+			file:  f,
+			title: t,
+			re:    regexp.MustCompile("(?i)" + regexp.QuoteMeta(t)),
+		})
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		if len(ms[i].title) != len(ms[j].title) {
+			return len(ms[i].title) > len(ms[j].title)
+		}
+		return ms[i].file < ms[j].file
+	})
+	return ms
+}
+
+// lineMentions returns the mentions in the body of the node file,
+// leaving out fenced code blocks
+func lineMentions(lines []string, file string, matchers []titleMatcher) []Mention {
+	fenced := fencedLines(lines)
+	ms := make([]Mention, 0)
+	for i := bodyStart(lines); i < len(lines); i++ {
+		if !fenced[i] {
+			ms = append(ms, findMentions(lines[i], i+1, file, matchers)...)
+		}
+	}
+	return ms
+}
+
+// findMentions returns the mentions in a line, leaving out links,
+// wiki links, embeds, inline code and urls
+func findMentions(line string, lineNr int, file string, matchers []titleMatcher) []Mention {
+	taken := make([][]int, 0)
+	for _, re := range []*regexp.Regexp{linkExtractor, wikiLinkExtractor, wikiEmbedExtractor, inlineCodeExtractor, urlExtractor} {
+		taken = append(taken, re.FindAllStringIndex(line, -1)...)
+	}
+
+	ms := make([]Mention, 0)
+	for _, m := range matchers {
+		if m.file == file {
+			continue
+		}
+		for _, loc := range m.re.FindAllStringIndex(line, -1) {
+			s, e := loc[0], loc[1]
+			if !wordBounded(line, s, e) || overlaps(taken, s, e) {
+				continue
+			}
+			taken = append(taken, loc)
+			ms = append(ms, Mention{
+				Target: m.file,
+				Title:  m.title,
+				Text:   line[s:e],
+				Line:   lineNr,
+				Column: s + 1,
+			})
+		}
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Column < ms[j].Column
+	})
+	return ms
+}
+
+func wordBounded(s string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(s[:start])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(s) {
+		r, _ := utf8.DecodeRuneInString(s[end:])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func overlaps(spans [][]int, start, end int) bool {
+	for _, sp := range spans {
+		if start < sp[1] && sp[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyStart returns the index of the first line after the front matter
+func bodyStart(lines []string) int {
+	if len(lines) == 0 || lines[0] != yamlFmDelim {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == yamlFmDelim {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func readLines(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(b), "\n"), nil
+}
+
+func writeLines(path string, lines []string) error {
+	return writeFileAtomic(path, []byte(strings.Join(lines, "\n")), 0644)
+}
@@ -0,0 +1,103 @@
+package network
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testMatchers() []titleMatcher {
+	return titleMatchers(map[string]Node{
+		"go.md":        {Title: "Go", File: "go.md"},
+		"go-tools.md":  {Title: "Go tools", File: "go-tools.md"},
+		"graph.md":     {Title: "Graph theory", File: "graph.md"},
+		"untitled.md":  {Title: " ", File: "untitled.md"},
+		"this-note.md": {Title: "Note", File: "this-note.md"},
+	})
+}
+
+func TestFindMentions(t *testing.T) {
+	type mention struct {
+		target string
+		text   string
+		column int
+	}
+	tests := []struct {
+		name string
+		line string
+		want []mention
+	}{
+		{"plain", "learning graph theory today",
+			[]mention{{"graph.md", "graph theory", 10}}},
+		{"case", "GRAPH THEORY and Go",
+			[]mention{{"graph.md", "GRAPH THEORY", 1}, {"go.md", "Go", 18}}},
+		{"longest title wins", "the go tools are neat",
+			[]mention{{"go-tools.md", "go tools", 5}}},
+		{"word boundaries", "gopher going ago go_on go2 algo",
+			nil},
+		{"punctuation bounds", "(go), go.",
+			[]mention{{"go.md", "go", 2}, {"go.md", "go", 7}}},
+		{"unicode bounds", "égo go",
+			[]mention{{"go.md", "go", 6}}},
+		{"markdown link", "see [graph theory](graph.md) and go",
+			[]mention{{"go.md", "go", 34}}},
+		{"link text containing a title", "[about go](other.md)",
+			nil},
+		{"wiki link", "see [[Graph theory]] and [[go]]",
+			nil},
+		{"embed", "![[go tools]]",
+			nil},
+		{"inline code", "run `go vet` and ``go ` build`` then go",
+			[]mention{{"go.md", "go", 38}}},
+		{"url", "https://go.dev/doc and www.go.example.com/go, go",
+			[]mention{{"go.md", "go", 47}}},
+		{"own title", "this note", nil},
+		{"blank title", "a   b", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := findMentions(tt.line, 3, "this-note.md", testMatchers())
+			got := make([]mention, 0)
+			for _, m := range ms {
+				if m.Line != 3 {
+					t.Errorf("line = %d, want 3", m.Line)
+				}
+				if tt.line[m.Column-1:m.Column-1+len(m.Text)] != m.Text {
+					t.Errorf("column %d doesn't point at %q", m.Column, m.Text)
+				}
+				got = append(got, mention{m.Target, m.Text, m.Column})
+			}
+			want := tt.want
+			if want == nil {
+				want = []mention{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("findMentions(%q) = %v, want %v", tt.line, got, want)
+			}
+		})
+	}
+}
+
+func TestLineMentionsSkipsFencedCode(t *testing.T) {
+	lines := strings.Split(`---
+title: Go
+---
+graph theory
+`+"```go"+`
+graph theory
+`+"```"+`
+  ~~~
+graph theory
+  ~~~
+graph theory`, "\n")
+
+	ms := lineMentions(lines, "go.md", testMatchers())
+	got := make([]int, 0)
+	for _, m := range ms {
+		got = append(got, m.Line)
+	}
+	if want := []int{4, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines of mentions = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package network
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/metrics"
+)
+
+var (
+	nodesCreated = metrics.NewCounter("zhuyi_nodes_created_total",
+		"Nodes created, by the api, the journal and imports.", "network")
+	nodesDeleted = metrics.NewCounter("zhuyi_nodes_deleted_total",
+		"Nodes deleted.", "network")
+	graphBuildSeconds = metrics.NewHistogram("zhuyi_graph_build_duration_seconds",
+		"Time taken to build the graph of the network.", metrics.DefaultBuckets, "network", "format")
+)
+
+func (c *Config) nodesCreated(n int) {
+	if n > 0 {
+		nodesCreated.Add(float64(n), c.Name)
+	}
+}
+
+// observeGraphBuild is deferred by what builds the graph as format
+func (c *Config) observeGraphBuild(format string, start time.Time) {
+	graphBuildSeconds.Observe(time.Since(start).Seconds(), c.Name, format)
+}
+
+// CountNodes returns how many nodes there are in the network
+func (c *Config) CountNodes() (int, error) {
+	files, err := ioutil.ReadDir(c.NetworkPath)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), mdExtension) {
+			n++
+		}
+	}
+	return n, nil
+}
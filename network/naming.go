@@ -0,0 +1,121 @@
+package network
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kraem/zhuyi-go/pkg/id"
+)
+
+const timeFormatFileSeconds = "060102-150405"
+const timeFormatFileNano = "060102-150405.000000000"
+
+// maxNameAttempts guards against a Namer which never
+// comes up with a free name
+const maxNameAttempts = 1000
+
+// Naming strategies, see NewNamer
+const (
+	NamingTimestamp = "timestamp"
+	NamingULID      = "ulid"
+	NamingUUID      = "uuid"
+	NamingSlug      = "slug"
+)
+
+// Namer picks file names (without the md extension) for new nodes.
+// Name is called again with an increasing attempt for as long as the
+// name it returned is taken, so it has to keep coming up with new ones.
+type Namer interface {
+	Name(title string, t time.Time, attempt int) (string, error)
+}
+
+// NewNamer returns the Namer of a naming strategy:
+// "timestamp" (default), "ulid", "uuid" or "slug"
+func NewNamer(strategy string) (Namer, error) {
+	switch strategy {
+	case "", NamingTimestamp:
+		return TimestampNamer{}, nil
+	case NamingULID:
+		return ULIDNamer{}, nil
+	case NamingUUID:
+		return UUIDNamer{}, nil
+	case NamingSlug:
+		return SlugNamer{}, nil
+	}
+	return nil, errorf(ErrInvalid, "invalid naming strategy: %v", strategy)
+}
+
+// TimestampNamer names nodes after the minute they're created, 060102-1504.
+// Taken names get a letter suffix, a-z, then the seconds and finally the
+// nanoseconds are added, followed by a counter if even those are taken.
+type TimestampNamer struct{}
+
+func (TimestampNamer) Name(title string, t time.Time, attempt int) (string, error) {
+	switch {
+	case attempt == 0:
+		return t.Format(timeFormatFile), nil
+	case attempt <= len(abc):
+		return t.Format(timeFormatFile) + string(abc[attempt-1]), nil
+	case attempt == len(abc)+1:
+		return t.Format(timeFormatFileSeconds), nil
+	case attempt == len(abc)+2:
+		return t.Format(timeFormatFileNano), nil
+	}
+	return t.Format(timeFormatFileNano) + "-" + strconv.Itoa(attempt-len(abc)-2), nil
+}
+
+// ULIDNamer names nodes with ULIDs, which sort by creation time
+type ULIDNamer struct{}
+
+func (ULIDNamer) Name(title string, t time.Time, attempt int) (string, error) {
+	return id.NewULID(t)
+}
+
+// UUIDNamer names nodes with random UUIDs
+type UUIDNamer struct{}
+
+func (UUIDNamer) Name(title string, t time.Time, attempt int) (string, error) {
+	return id.NewUUID()
+}
+
+// SlugNamer names nodes after their title, "My Note!" becomes my-note,
+// followed by a counter when taken: my-note-2, my-note-3, ...
+// Nodes without a title are named by the TimestampNamer.
+type SlugNamer struct{}
+
+func (SlugNamer) Name(title string, t time.Time, attempt int) (string, error) {
+	s := slugFileName(title)
+	if s == "" {
+		return TimestampNamer{}.Name(title, t, attempt)
+	}
+	if attempt == 0 {
+		return s, nil
+	}
+	return s + "-" + strconv.Itoa(attempt+1), nil
+}
+
+func slugFileName(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+func (c *Config) namer() Namer {
+	if c.Namer == nil {
+		return TimestampNamer{}
+	}
+	return c.Namer
+}
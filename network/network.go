@@ -2,10 +2,8 @@ package network
 
 import (
 	"bufio"
-	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -19,9 +17,15 @@ import (
 const yamlFmDelim = "---"
 const yamlFmTitleField = "title: "
 const yamlFmDateField = "date: "
+const yamlFmRootField = "root"
+const yamlFmTagsField = "tags"
 
 const mdExtension = ".md"
 
+// writeFileAtomic writes every node, tests swap it
+// out to have writes fail, see fs.WriteFileAtomic
+var writeFileAtomic = fs.WriteFileAtomic
+
 const index = "index"
 
 const timeFormatFile = "060102-1504"
@@ -38,7 +42,7 @@ const abc = "abcdefghijklmnopqrstuvwxyz"
 // x+ matches x one or more times (same as x{1,})
 // x? matches x zero or one time (same as x{0,1})
 var typeExtractor = regexp.MustCompile("(.*): (.*)")
-var linkExtractor = regexp.MustCompile(`\[([^\[]*)\](\((.*)\))`)
+var linkExtractor = regexp.MustCompile(`\[([^\[\]]*)\]\(([^)]*)\)`)
 
 type Node struct {
 	// TODO
@@ -47,14 +51,19 @@ type Node struct {
 	Date  string   `json:"date"`
 	Title string   `json:"title"`
 	File  string   `json:"file"`
+	Root  bool     `json:"root,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
 	Links []string `json:"links"`
+	Edges []Edge   `json:"-"`
 }
 
-func extractMarkdownLinks(path string) (links []string, err error) {
+// extractMarkdownLinks returns the raw targets of the links in the file
+// and the node file names of what it embeds, ![[note]] or ![x](note.md)
+func extractMarkdownLinks(path string) (links, embeds []string, err error) {
 
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
@@ -62,11 +71,22 @@ func extractMarkdownLinks(path string) (links []string, err error) {
 	scanner.Split(bufio.ScanLines)
 
 	for scanner.Scan() {
+		line := scanner.Text()
 
-		tokens := linkExtractor.FindStringSubmatch(scanner.Text())
+		for _, loc := range linkExtractor.FindAllStringSubmatchIndex(line, -1) {
+			raw := line[loc[4]:loc[5]]
+			if loc[0] > 0 && line[loc[0]-1] == '!' {
+				if target, _ := parseLink(raw); strings.HasSuffix(target, mdExtension) {
+					embeds = append(embeds, target)
+					continue
+				}
+			}
+			links = append(links, raw)
+		}
 
-		if len(tokens) > 1 {
-			links = append(links, tokens[3])
+		for _, tokens := range wikiEmbedExtractor.FindAllStringSubmatch(line, -1) {
+			target, _ := parseWikiLink(tokens[1])
+			embeds = append(embeds, target)
 		}
 	}
 
@@ -101,67 +121,44 @@ func extractFrontMatterFields(path string) (fields map[string]string, err error)
 	return fields, nil
 }
 
+// parseTags parses the front matter tag list, [a, b]
+func parseTags(s string) []string {
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "["), "]")
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
 func (c *Config) UnlinkedNodes() ([]Node, error) {
-	fim, err := c.mapFileToInt(true)
+	ns, err := c.linksPerFilename()
 	if err != nil {
 		return nil, err
 	}
 
-	indexNode := fim.fileToInt[c.NetworkPath+index+mdExtension]
-	walked := make(map[int]bool, len(fim.fileToInt))
-	c.walkGraph(indexNode, fim, walked)
-
-	ns, err := nodesFromWalked(walked, fim)
+	roots, err := c.rootNodes(ns)
 	if err != nil {
 		return nil, err
 	}
-	sortNodesDate(ns)
-	return ns, nil
-}
 
-// walkGraph walks the graph depth first
-func (c *Config) walkGraph(n int, g *fileIntMap, walked map[int]bool) {
-	walked[n] = true
-	fn := g.intToFile[n]
-	if !strings.HasSuffix(fn, mdExtension) {
-		return
-	}
-	links, err := extractMarkdownLinks(fn)
-	if err != nil {
-		log.LogError(err)
-		return
-	}
-	for _, l := range links {
-		lp := c.NetworkPath + l
-		li := g.fileToInt[lp]
-		if !walked[li] {
-			c.walkGraph(li, g, walked)
+	adj := localAdjacency(ns)
+	walked := make(map[string]bool, len(ns))
+	for _, r := range roots {
+		for f := range depthsFrom(adj, r) {
+			walked[f] = true
 		}
 	}
-}
 
-func nodesFromWalked(walked map[int]bool, fim *fileIntMap) ([]Node, error) {
 	unlinked := make([]Node, 0)
-	for k, v := range fim.intToFile {
-		if !walked[k] {
-			fmFields, err := extractFrontMatterFields(v)
-			if err != nil {
-				log.LogError(err)
-				continue
-			}
-			_, fileName := path.Split(v)
-			n := Node{
-				Title: fmFields["title"],
-				File:  fileName,
-				Date:  fmFields["date"],
-				// TODO
-				// operate on nodes like objects will make
-				// this come by itself
-				//Links: links,
-			}
+	for f, n := range ns {
+		if !walked[f] {
 			unlinked = append(unlinked, n)
 		}
 	}
+	sortNodesDate(unlinked)
 	return unlinked, nil
 }
 
@@ -249,7 +246,7 @@ func (c *Config) buildAdjacencyMatrix(fim fileIntMap) ([][]int, map[string]Node,
 			continue
 		}
 
-		links, err := extractMarkdownLinks(fullPath)
+		links, _, err := extractMarkdownLinks(fullPath)
 		if err != nil {
 			log.LogError(err)
 			continue
@@ -349,84 +346,139 @@ func (c *Config) mapFileToInt(fullPath bool) (*fileIntMap, error) {
 	return &fim, nil
 }
 
-func (c *Config) DelNode(filename string) error {
-	fp := filepath.Join(c.NetworkPath, filename)
-	exist, err := fs.PathExists(fp)
+// notePath returns the full path of a node in the network,
+// refusing anything which isn't a plain md file name.
+func (c *Config) notePath(fileName string) (string, error) {
+	if fileName != filepath.Base(fileName) || !strings.HasSuffix(fileName, mdExtension) {
+		err := errorf(ErrInvalid, "invalid node file name: %v", fileName)
+		return "", err
+	}
+	return filepath.Join(c.NetworkPath, fileName), nil
+}
+
+// writeNewFile writes a file which mustn't exist, returning an os.IsExist
+// error if it does. The name is reserved by creating the file exclusively,
+// so concurrent creates can't pick the same one. The content is then
+// renamed into place so nobody sees a partially written node.
+func writeNewFile(path string, content []byte) error {
+	f, err := fs.CreateExclusive(path, 0644)
 	if err != nil {
 		return err
 	}
-	// TODO
-	// this might not be needed
-	// as os.Remove calls unlink..
-	if !exist {
-		err := fmt.Errorf("file doesn't exist: %v", fp)
+	if err := f.Close(); err != nil {
+		os.Remove(path)
 		return err
 	}
-	err = os.Remove(fp)
-	if err != nil {
+	if err := writeFileAtomic(path, content, 0644); err != nil {
+		os.Remove(path)
 		return err
 	}
 	return nil
 }
 
-func (c *Config) CreateNode(title, body string) (fileName string, err error) {
-	timeNow := time.Now()
-	nodeDateFm := timeNow.Format(timeFormatFm)
-	nodeFileName := timeNow.Format(timeFormatFile)
-	nodeFilePath := c.NetworkPath + nodeFileName + mdExtension
-
-	exist, err := fs.PathExists(nodeFilePath)
+func (c *Config) DelNode(filename string) error {
+	fp, err := c.notePath(filename)
 	if err != nil {
-		err := fmt.Errorf("file already exists")
-		return "", err
+		return err
 	}
 
-	if exist {
-		for i := range abc {
-			if i == 0 {
-				nodeFileName = nodeFileName + string(abc[i])
-				nodeFilePath = c.NetworkPath + nodeFileName + mdExtension
-			} else if i == (len(abc) - 1) {
-				err := fmt.Errorf("too many files created in one minute")
-				log.LogError(err)
-				return "", err
-			} else {
-				runes := []rune(nodeFileName)
-				runes[len(nodeFileName)-1] = rune(abc[i])
-				nodeFileName = string(runes)
-				nodeFilePath = c.NetworkPath + nodeFileName + mdExtension
-			}
-			exist, err = fs.PathExists(nodeFilePath)
-			if err != nil {
-				log.LogError(err)
-				return "", err
-			}
-			if exist {
-				continue
-			}
-			break
+	unlock := c.locks.lock(filename)
+	defer unlock()
+
+	if err := os.Remove(fp); err != nil {
+		return nodeError(filename, err)
+	}
+	nodesDeleted.Inc(c.Name)
+	return nil
+}
+
+func (c *Config) CreateNode(title, body string) (fileName string, err error) {
+	fileName, _, err = c.CreateNodeFrom(NodeOptions{
+		Title: title,
+		Body:  body,
+	})
+	return fileName, err
+}
+
+// CreateNodeFrom creates a node from a template, see templatesDir,
+// linking it from its parent if it has one. It returns the file name
+// of the new node and every file it changed.
+func (c *Config) CreateNodeFrom(o NodeOptions) (fileName string, changed []string, err error) {
+	if o.Parent != "" {
+		fileName, changed, err = c.linkFromParent(o, func() (string, error) {
+			return c.createNode(o)
+		})
+		if err == nil {
+			c.nodesCreated(1)
 		}
+		return fileName, changed, err
+	}
 
+	fileName, err = c.createNode(o)
+	if err != nil {
+		return "", nil, err
 	}
+	c.nodesCreated(1)
+	return fileName, []string{fileName}, nil
+}
 
-	f, err := os.Create(nodeFilePath)
+func (c *Config) createNode(o NodeOptions) (fileName string, err error) {
+	timeNow := time.Now()
+	content, err := c.renderNode(o, timeNow)
 	if err != nil {
 		log.LogError(err)
 		return "", err
 	}
-	defer f.Close()
+	if err := c.checkNoteSize("the node", len(content)); err != nil {
+		return "", err
+	}
 
-	f.Write([]byte(yamlFmDelim + "\n"))
-	f.Write([]byte(yamlFmTitleField + title + "\n"))
-	f.Write([]byte(yamlFmDateField + nodeDateFm + "\n"))
-	f.Write([]byte(yamlFmDelim + "\n\n"))
-	f.Write([]byte(body + "\n"))
+	nodeFileName, err := c.reserveNode(o.Title, timeNow)
+	if err != nil {
+		log.LogError(err)
+		return "", err
+	}
 
-	nodeFileName = nodeFileName + mdExtension
+	if err := writeFileAtomic(c.NetworkPath+nodeFileName, content, 0644); err != nil {
+		log.LogError(err)
+		os.Remove(c.NetworkPath + nodeFileName)
+		return "", err
+	}
 
 	return nodeFileName, nil
 }
 
+// reserveNode picks a file name for a new node with the Namer and
+// reserves it by creating the (empty) file exclusively, so concurrent
+// creates can't pick the same one.
+func (c *Config) reserveNode(title string, t time.Time) (fileName string, err error) {
+	for attempt := 0; ; attempt++ {
+		if attempt == maxNameAttempts {
+			err := errorf(ErrConflict, "no free file name found after %d attempts", attempt)
+			return "", err
+		}
+
+		fileName, err = c.namer().Name(title, t, attempt)
+		if err != nil {
+			return "", err
+		}
+		fileName = fileName + mdExtension
+
+		f, err := fs.CreateExclusive(c.NetworkPath+fileName, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(c.NetworkPath + fileName)
+			return "", err
+		}
+		return fileName, nil
+	}
+}
+
 // TODO
 // D3 area
 // 1. extract to own module?
@@ -444,13 +496,17 @@ type D3Node struct {
 }
 
 type D3Link struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
-	Value  string `json:"value"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Value    string `json:"value"`
+	Weight   int    `json:"weight"`
+	Kind     string `json:"kind"`
+	SelfLoop bool   `json:"self_loop,omitempty"`
 }
 
 func (c *Config) CreateD3jsGraph() (*D3jsGraph, error) {
-	filenameToNode, err := linksPerFilename(c.NetworkPath)
+	defer c.observeGraphBuild("d3", time.Now())
+	filenameToNode, err := c.linksPerFilename()
 	if err != nil {
 		return nil, err
 	}
@@ -470,7 +526,8 @@ func (c *Config) CreateD3jsGraph() (*D3jsGraph, error) {
 			created[f] = true
 		}
 
-		for _, l := range n.Links {
+		for _, e := range n.Edges {
+			l := e.Target
 			// TODO
 			// this is ugly!
 			// we need to differantiate between http links
@@ -523,9 +580,12 @@ func (c *Config) CreateD3jsGraph() (*D3jsGraph, error) {
 				created[l] = true
 			}
 			link := D3Link{
-				Source: n.File,
-				Target: l,
-				Value:  "2",
+				Source:   n.File,
+				Target:   l,
+				Value:    "2",
+				Weight:   e.Weight,
+				Kind:     string(e.Kind),
+				SelfLoop: e.SelfLoop,
 			}
 			g.Links = append(g.Links, link)
 		}
@@ -536,8 +596,11 @@ func (c *Config) CreateD3jsGraph() (*D3jsGraph, error) {
 // returns hash map of file names
 // each filename contains a node object
 // which in turn includes all of its
-// filenames/http-links it links to
-func linksPerFilename(path string) (map[string]Node, error) {
+// filenames/http-links it links to,
+// normalized and collapsed into weighted edges
+func (c *Config) linksPerFilename() (map[string]Node, error) {
+	path := c.NetworkPath
+
 	// TODO
 	// a better solution would be to walk the filesystem and build up nodes with
 	// their filename, title, etc
@@ -553,46 +616,59 @@ func linksPerFilename(path string) (map[string]Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	seen := make(map[string]bool, len(files))
 	for _, f := range files {
 		fileName := f.Name()
 
 		if !strings.HasSuffix(fileName, mdExtension) {
 			continue
 		}
+		seen[fileName] = true
 
-		fullPath := filepath.Join(path, fileName)
+		cached, exists := c.cache.get(f)
+		if !exists {
+			fullPath := filepath.Join(path, fileName)
 
-		fmFields, err := extractFrontMatterFields(fullPath)
-		if err != nil {
-			log.LogError(err)
-			continue
-		}
+			fmFields, err := extractFrontMatterFields(fullPath)
+			if err != nil {
+				log.LogError(err)
+				continue
+			}
 
-		// TODO
-		// this is probably not we want..
-		//if _, exists := fmFields["title"]; !exists {
-		//	continue
-		//}
-
-		// TODO
-		// check for duplicate links
-		// tested against `/test/network_1`, it doesn't error out,
-		// but we get unnecessary svg lines between nodes.
-		links, err := extractMarkdownLinks(fullPath)
-		if err != nil {
-			log.LogError(err)
-			continue
+			// TODO
+			// this is probably not we want..
+			//if _, exists := fmFields["title"]; !exists {
+			//	continue
+			//}
+
+			links, embeds, err := extractMarkdownLinks(fullPath)
+			if err != nil {
+				log.LogError(err)
+				continue
+			}
+
+			cached = cachedNode{fmFields: fmFields, links: links, embeds: embeds}
+			c.cache.put(f, cached)
 		}
+		fmFields := cached.fmFields
+
+		// duplicate links are collapsed into weighted edges
+		// so we don't get unnecessary svg lines between nodes.
+		edges := buildEdges(fileName, cached.links, cached.embeds, c.SelfLoops)
 
 		n := Node{
 			Title: fmFields["title"],
 			File:  fileName,
 			Date:  fmFields["date"],
-			Links: links,
+			Root:  fmFields[yamlFmRootField] == "true",
+			Tags:  parseTags(fmFields[yamlFmTagsField]),
+			Links: edgeTargets(edges),
+			Edges: edges,
 		}
 
 		fileToNodeMap[fileName] = n
 	}
+	c.cache.retain(seen)
 
 	return fileToNodeMap, nil
 }
@@ -0,0 +1,195 @@
+package network
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/config"
+)
+
+// testNetwork sets up an empty network in a temporary directory
+func testNetwork(t *testing.T) *Config {
+	t.Helper()
+	c, err := NewConfigFrom(config.Network{
+		Name:        "test",
+		NetworkPath: t.TempDir(),
+		SelfLoops:   string(SelfLoopsFlag),
+	}, config.Cache{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// files returns the names of the files in the network
+func files(t *testing.T, c *Config) []string {
+	t.Helper()
+	fis, err := ioutil.ReadDir(c.NetworkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	return names
+}
+
+// constantNamer comes up with the same name every time
+type constantNamer struct{}
+
+func (constantNamer) Name(title string, t time.Time, attempt int) (string, error) {
+	return "same", nil
+}
+
+func TestCreateNodeConcurrently(t *testing.T) {
+	for _, strategy := range []string{NamingTimestamp, NamingSlug, NamingULID} {
+		t.Run(strategy, func(t *testing.T) {
+			c := testNetwork(t)
+			namer, err := NewNamer(strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Namer = namer
+
+			const n = 300
+			names := make([]string, n)
+			errs := make([]error, n)
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					names[i], errs[i] = c.CreateNode("same title", "body")
+				}(i)
+			}
+			wg.Wait()
+
+			seen := make(map[string]bool)
+			for i := 0; i < n; i++ {
+				if errs[i] != nil {
+					t.Fatalf("create %d: %v", i, errs[i])
+				}
+				if seen[names[i]] {
+					t.Fatalf("%v was created twice", names[i])
+				}
+				seen[names[i]] = true
+			}
+
+			fs := files(t, c)
+			if len(fs) != n {
+				t.Errorf("%d files in the network, want %d", len(fs), n)
+			}
+			for _, f := range fs {
+				if !seen[f] {
+					t.Errorf("file left behind: %v", f)
+					continue
+				}
+				b, err := ioutil.ReadFile(c.NetworkPath + f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(b), "title: same title") {
+					t.Errorf("%v wasn't written: %q", f, b)
+				}
+			}
+		})
+	}
+}
+
+func TestReserveNodeExhausted(t *testing.T) {
+	c := testNetwork(t)
+	c.Namer = constantNamer{}
+
+	first, err := c.CreateNode("a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != "same.md" {
+		t.Errorf("created %v, want same.md", first)
+	}
+
+	_, err = c.CreateNode("b", "")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+	if fs := files(t, c); len(fs) != 1 {
+		t.Errorf("files = %v, want only %v", fs, first)
+	}
+}
+
+// swapWrites has fn write the nodes, returning the function
+// restoring writeFileAtomic, which is also called when the test ends
+func swapWrites(t *testing.T, fn func(path string, data []byte, perm os.FileMode) error) (restore func()) {
+	orig := writeFileAtomic
+	writeFileAtomic = fn
+	restore = func() {
+		writeFileAtomic = orig
+	}
+	t.Cleanup(restore)
+	return restore
+}
+
+func TestCreateNodeCleansUpFailedWrite(t *testing.T) {
+	c := testNetwork(t)
+
+	failed := errors.New("disk full")
+	restore := swapWrites(t, func(path string, data []byte, perm os.FileMode) error {
+		return failed
+	})
+
+	if _, err := c.CreateNode("a", "body"); !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want %v", err, failed)
+	}
+	if fs := files(t, c); len(fs) != 0 {
+		t.Errorf("files left behind: %v", fs)
+	}
+
+	// the name is free again
+	restore()
+	if _, err := c.CreateNode("a", "body"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMissingNodeErrors(t *testing.T) {
+	c := testNetwork(t)
+	writeNodes(t, c, map[string]string{"a.md": "---\ntitle: A\n---\na\n"})
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"ReadNode", func() error { _, err := c.ReadNode("nope.md"); return err }},
+		{"AppendNode", func() error { return c.AppendNode("nope.md", "", "text") }},
+		{"Outline", func() error { _, err := c.Outline("nope.md"); return err }},
+		{"LinkMention", func() error { return c.LinkMention("nope.md", "a.md", 4, 1) }},
+		{"LinkMention target", func() error { return c.LinkMention("a.md", "nope.md", 4, 1) }},
+		{"DelNode", func() error { return c.DelNode("nope.md") }},
+		{"CreateNodeFrom parent", func() error {
+			_, _, err := c.CreateNodeFrom(NodeOptions{Title: "b", Parent: "nope.md"})
+			return err
+		}},
+		{"exportNode", func() error { _, err := c.exportNode("nope.md"); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("err = %v, want ErrNotFound", err)
+			}
+			if err != nil && strings.Contains(err.Error(), c.NetworkPath) {
+				t.Errorf("err has the path of the network: %v", err)
+			}
+		})
+	}
+	if fs := files(t, c); len(fs) != 1 {
+		t.Errorf("files = %v, want only a.md", fs)
+	}
+}
@@ -0,0 +1,232 @@
+package network
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+var headingExtractor = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+
+// Heading is a markdown heading of a node together with
+// the headings nested under it.
+type Heading struct {
+	Level    int        `json:"level"`
+	Text     string     `json:"text"`
+	Anchor   string     `json:"anchor"`
+	Line     int        `json:"line"`
+	Children []*Heading `json:"children,omitempty"`
+}
+
+// SectionLink is a link pointing to a heading of a node,
+// e.g. [x](note.md#some-heading). An empty Heading means
+// the anchor couldn't be resolved.
+type SectionLink struct {
+	Source   string `json:"source"`
+	Line     int    `json:"line"`
+	Target   string `json:"target"`
+	Fragment string `json:"fragment"`
+	Heading  string `json:"heading,omitempty"`
+}
+
+// Outline returns the heading tree of a node.
+func (c *Config) Outline(fileName string) ([]*Heading, error) {
+	fp, err := c.notePath(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readLines(fp)
+	if err != nil {
+		return nil, nodeError(fileName, err)
+	}
+	return headingTree(parseHeadings(lines)), nil
+}
+
+// SectionLinks resolves every link with a #fragment pointing
+// to a node in the network to the heading it refers to.
+func (c *Config) SectionLinks() ([]SectionLink, error) {
+	ns, err := c.linksPerFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	outlines := make(map[string][]Heading, len(ns))
+	bodies := make(map[string][]string, len(ns))
+	for f := range ns {
+		lines, err := readLines(filepath.Join(c.NetworkPath, f))
+		if err != nil {
+			log.LogError(err)
+			continue
+		}
+		bodies[f] = lines
+		outlines[f] = parseHeadings(lines)
+	}
+
+	sls := make([]SectionLink, 0)
+	for f, lines := range bodies {
+		for i := bodyStart(lines); i < len(lines); i++ {
+			for _, tokens := range linkExtractor.FindAllStringSubmatch(lines[i], -1) {
+				target, fragment := parseLink(tokens[2])
+				if fragment == "" || isExternalLink(target) {
+					continue
+				}
+				if target == "" {
+					target = f
+				}
+				hs, exists := outlines[target]
+				if !exists {
+					continue
+				}
+				sl := SectionLink{
+					Source:   f,
+					Line:     i + 1,
+					Target:   target,
+					Fragment: fragment,
+				}
+				if h := findHeading(hs, fragment); h != nil {
+					sl.Heading = h.Text
+				}
+				sls = append(sls, sl)
+			}
+		}
+	}
+
+	sort.Slice(sls, func(i, j int) bool {
+		if sls[i].Source != sls[j].Source {
+			return sls[i].Source < sls[j].Source
+		}
+		return sls[i].Line < sls[j].Line
+	})
+
+	return sls, nil
+}
+
+// BrokenAnchors returns the section links whose
+// heading doesn't exist in the node they point to.
+func (c *Config) BrokenAnchors() ([]SectionLink, error) {
+	sls, err := c.SectionLinks()
+	if err != nil {
+		return nil, err
+	}
+	broken := make([]SectionLink, 0)
+	for _, sl := range sls {
+		if sl.Heading == "" {
+			broken = append(broken, sl)
+		}
+	}
+	return broken, nil
+}
+
+// fencedLines tells which lines are part of fenced code
+// blocks, the lines of the fences included
+func fencedLines(lines []string) []bool {
+	fenced := make([]bool, len(lines))
+	fence := ""
+	for i := bodyStart(lines); i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		switch {
+		case fence != "":
+			fenced[i] = true
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fenced[i] = true
+			fence = trimmed[:3]
+		}
+	}
+	return fenced
+}
+
+// parseHeadings returns the headings of a node in order of appearance,
+// leaving out anything inside fenced code blocks.
+func parseHeadings(lines []string) []Heading {
+	hs := make([]Heading, 0)
+	anchors := make(map[string]int)
+	fenced := fencedLines(lines)
+
+	for i := bodyStart(lines); i < len(lines); i++ {
+		if fenced[i] {
+			continue
+		}
+		l := strings.TrimRight(lines[i], "\r")
+
+		tokens := headingExtractor.FindStringSubmatch(l)
+		if tokens == nil {
+			continue
+		}
+
+		anchor := slugify(tokens[2])
+		if n := anchors[anchor]; n > 0 {
+			anchors[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			anchors[anchor] = 1
+		}
+
+		hs = append(hs, Heading{
+			Level:  len(tokens[1]),
+			Text:   tokens[2],
+			Anchor: anchor,
+			Line:   i + 1,
+		})
+	}
+
+	return hs
+}
+
+// headingTree nests every heading under the closest
+// preceding heading of a lower level.
+func headingTree(hs []Heading) []*Heading {
+	tree := make([]*Heading, 0)
+	stack := make([]*Heading, 0)
+	for i := range hs {
+		h := &hs[i]
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			tree = append(tree, h)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, h)
+		}
+		stack = append(stack, h)
+	}
+	return tree
+}
+
+func findHeading(hs []Heading, fragment string) *Heading {
+	s := slugify(fragment)
+	for i := range hs {
+		if hs[i].Anchor == fragment || hs[i].Anchor == s {
+			return &hs[i]
+		}
+	}
+	return nil
+}
+
+// HeadingAnchor returns the anchor links to a heading use, see slugify
+func HeadingAnchor(heading string) string {
+	return slugify(heading)
+}
+
+// slugify creates an anchor from a heading the way github does:
+// lower case, punctuation removed and spaces replaced by dashes.
+func slugify(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package network
+
+import (
+	"os"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// Where in the parent (or its heading) the link to a new node goes
+const (
+	PositionLast  = "last"
+	PositionFirst = "first"
+)
+
+// linkFromParent creates the node through create and links it from the
+// parent. If the parent can't be updated the node is removed again, so
+// either both files change or none of them.
+func (c *Config) linkFromParent(o NodeOptions, create func() (string, error)) (string, []string, error) {
+	parentPath, err := c.notePath(o.Parent)
+	if err != nil {
+		return "", nil, err
+	}
+
+	unlock := c.locks.lock(o.Parent)
+	defer unlock()
+
+	lines, err := readLines(parentPath)
+	if err != nil {
+		return "", nil, nodeError(o.Parent, err)
+	}
+	// find out if the heading exists before creating anything
+	if _, err := insertListItem(lines, "", o.ParentHeading, o.ParentPosition); err != nil {
+		return "", nil, err
+	}
+
+	fileName, err := create()
+	if err != nil {
+		return "", nil, err
+	}
+
+	title := o.Title
+	if title == "" {
+		title = fileName
+	}
+	lines, err = insertListItem(lines, "["+title+"]("+fileName+")", o.ParentHeading, o.ParentPosition)
+	if err == nil {
+		err = writeFileAtomic(parentPath, []byte(strings.Join(lines, "\n")), 0644)
+	}
+	if err != nil {
+		log.LogError(err)
+		if err := os.Remove(c.NetworkPath + fileName); err != nil {
+			log.LogError(err)
+		}
+		return "", nil, err
+	}
+
+	return fileName, []string{fileName, o.Parent}, nil
+}
+
+// insertListItem adds `- item` to the section under heading, above any
+// nested headings, or to the whole body when heading is empty. If the section has a list the item goes
+// first or last in it, depending on position, otherwise it goes last
+// in the section.
+func insertListItem(lines []string, item, heading, position string) ([]string, error) {
+	switch position {
+	case "", PositionLast, PositionFirst:
+	default:
+		return nil, errorf(ErrInvalid, "invalid position: %v", position)
+	}
+
+	start, end, err := sectionBounds(lines, heading)
+	if err != nil {
+		return nil, err
+	}
+
+	firstItem, lastItem := -1, -1
+	for i := start; i < end; i++ {
+		if isListItem(lines[i]) {
+			if firstItem < 0 {
+				firstItem = i
+			}
+			lastItem = i
+		}
+	}
+
+	var at int
+	var insert []string
+	switch {
+	case firstItem >= 0 && position == PositionFirst:
+		at = firstItem
+		insert = []string{"- " + item}
+	case firstItem >= 0:
+		at = lastItem + 1
+		insert = []string{"- " + item}
+	default:
+		// after the last non blank line of the section
+		at = end
+		for at > start && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		insert = []string{"- " + item}
+		if at > start || start > 0 {
+			insert = append([]string{""}, insert...)
+		}
+	}
+
+	res := make([]string, 0, len(lines)+len(insert))
+	res = append(res, lines[:at]...)
+	res = append(res, insert...)
+	res = append(res, lines[at:]...)
+	return res, nil
+}
+
+// sectionBounds returns the line indexes [start, end) of the content
+// directly under heading, above any nested headings, or of the whole
+// body when heading is empty.
+func sectionBounds(lines []string, heading string) (int, int, error) {
+	start, end := bodyStart(lines), len(lines)
+	if heading == "" {
+		return start, end, nil
+	}
+
+	h, exists := findSection(lines, heading)
+	if !exists {
+		return 0, 0, errorf(ErrNotFound, "heading doesn't exist: %v", heading)
+	}
+	start, end = h[0], h[1]
+	for _, sub := range parseHeadings(lines) {
+		if sub.Line-1 >= start && sub.Line-1 < end {
+			end = sub.Line - 1
+			break
+		}
+	}
+	return start, end, nil
+}
+
+// findSection returns the line indexes [start, end) of the content under
+// the heading whose text or anchor matches heading, up until the next
+// heading of the same or a higher level.
+func findSection(lines []string, heading string) ([2]int, bool) {
+	hs := parseHeadings(lines)
+	for i, h := range hs {
+		if !strings.EqualFold(h.Text, heading) && h.Anchor != heading && h.Anchor != slugify(heading) {
+			continue
+		}
+		end := len(lines)
+		for _, next := range hs[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line - 1
+				break
+			}
+		}
+		return [2]int{h.Line, end}, true
+	}
+	return [2]int{}, false
+}
+
+func isListItem(l string) bool {
+	l = strings.TrimLeft(l, " \t")
+	return strings.HasPrefix(l, "- ") || strings.HasPrefix(l, "* ") || strings.HasPrefix(l, "+ ")
+}
+
+func isIndented(l string) bool {
+	return strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")
+}
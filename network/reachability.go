@@ -0,0 +1,145 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// RootDepth is the shortest distance from a root node to a node.
+type RootDepth struct {
+	Root  string `json:"root"`
+	Depth int    `json:"depth"`
+}
+
+// Reachability lists the root nodes a node can be reached from.
+// A node which isn't reachable from any root has no roots.
+type Reachability struct {
+	File  string      `json:"file"`
+	Title string      `json:"title"`
+	Roots []RootDepth `json:"roots"`
+}
+
+// Reachability reports, for every node, which roots reach it
+// and at what depth.
+func (c *Config) Reachability() ([]Reachability, error) {
+	ns, err := c.linksPerFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := c.rootNodes(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	adj := localAdjacency(ns)
+	depths := make(map[string][]RootDepth, len(ns))
+	for _, r := range roots {
+		for f, d := range depthsFrom(adj, r) {
+			depths[f] = append(depths[f], RootDepth{Root: r, Depth: d})
+		}
+	}
+
+	res := make([]Reachability, 0, len(ns))
+	for f, n := range ns {
+		rds := depths[f]
+		if rds == nil {
+			rds = make([]RootDepth, 0)
+		}
+		res = append(res, Reachability{
+			File:  f,
+			Title: n.Title,
+			Roots: rds,
+		})
+	}
+
+	// file names are the dates..
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].File > res[j].File
+	})
+
+	return res, nil
+}
+
+// rootNodes returns the file names of the root nodes in ns: the ones
+// configured (or index.md when none are) and the ones with
+// `root: true` in their front matter.
+func (c *Config) rootNodes(ns map[string]Node) ([]string, error) {
+	roots := make([]string, 0)
+	seen := make(map[string]bool)
+
+	configured := c.Roots
+	if len(configured) == 0 {
+		if _, exists := ns[index+mdExtension]; exists {
+			configured = []string{index + mdExtension}
+		}
+	}
+
+	for _, r := range configured {
+		if !strings.HasSuffix(r, mdExtension) {
+			r = r + mdExtension
+		}
+		if _, exists := ns[r]; !exists {
+			log.LogError(fmt.Errorf("configured root node doesn't exist: %v", r))
+			continue
+		}
+		if !seen[r] {
+			roots = append(roots, r)
+			seen[r] = true
+		}
+	}
+
+	fmRoots := make([]string, 0)
+	for f, n := range ns {
+		if n.Root && !seen[f] {
+			fmRoots = append(fmRoots, f)
+			seen[f] = true
+		}
+	}
+	sort.Strings(fmRoots)
+	roots = append(roots, fmRoots...)
+
+	if len(roots) == 0 {
+		err := errorf(ErrConflict, "no root nodes found in %v: set root_notes, add `root: true` to a node's front matter or create %v",
+			c.NetworkPath, index+mdExtension)
+		return nil, err
+	}
+
+	return roots, nil
+}
+
+// localAdjacency returns, per node, the nodes in the
+// network it links to (http links and such are left out)
+func localAdjacency(ns map[string]Node) map[string][]string {
+	adj := make(map[string][]string, len(ns))
+	for f, n := range ns {
+		for _, l := range n.Links {
+			if _, exists := ns[l]; exists {
+				adj[f] = append(adj[f], l)
+			}
+		}
+	}
+	return adj
+}
+
+// depthsFrom walks the graph breadth first from root and returns
+// the shortest depth of every node it reaches, root included.
+func depthsFrom(adj map[string][]string, root string) map[string]int {
+	depths := map[string]int{root: 0}
+	queue := []string{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, l := range adj[n] {
+			if _, walked := depths[l]; walked {
+				continue
+			}
+			depths[l] = depths[n] + 1
+			queue = append(queue, l)
+		}
+	}
+	return depths
+}
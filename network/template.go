@@ -0,0 +1,145 @@
+package network
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/fs"
+	"github.com/kraem/zhuyi-go/pkg/id"
+)
+
+// templatesDir is where node templates live, relative to the network.
+// A template is a md file executed with text/template, e.g.
+//
+//	---
+//	title: {{.Title}}
+//	date: {{.Date}}
+//	tags: [{{.Tags}}]
+//	---
+//
+//	up: {{.ParentLink}}
+//
+//	{{.Body}}
+const templatesDir = ".templates"
+
+// defaultTemplate is used when no template is asked for, if it exists
+const defaultTemplate = "default"
+
+const builtinTemplate = yamlFmDelim + `
+` + yamlFmTitleField + `{{.Title}}
+` + yamlFmDateField + `{{.Date}}
+{{if .Tags}}tags: [{{.Tags}}]
+{{end}}` + yamlFmDelim + `
+
+{{.Body}}
+`
+
+// NodeOptions describes a node to create. When Parent is set a link
+// to the new node is added to the parent, under ParentHeading if set,
+// first or last (default) in its list, see PositionFirst.
+type NodeOptions struct {
+	Title          string
+	Body           string
+	Template       string
+	Tags           []string
+	Parent         string
+	ParentHeading  string
+	ParentPosition string
+}
+
+// templateData holds the placeholders available in templates
+type templateData struct {
+	Title      string
+	Date       string
+	UUID       string
+	Body       string
+	Tags       string
+	TagList    []string
+	Parent     string
+	ParentLink string
+}
+
+// renderNode executes the template asked for in o, the default
+// template of the network or the builtin one, in that order.
+func (c *Config) renderNode(o NodeOptions, t time.Time) ([]byte, error) {
+	tmpl, err := c.loadTemplate(o.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	uuid, err := id.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	data := templateData{
+		Title:   o.Title,
+		Date:    t.Format(timeFormatFm),
+		UUID:    uuid,
+		Body:    o.Body,
+		Tags:    strings.Join(o.Tags, ", "),
+		TagList: o.Tags,
+		Parent:  o.Parent,
+	}
+	if o.Parent != "" {
+		data.ParentLink, err = c.parentLink(o.Parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Config) loadTemplate(name string) (*template.Template, error) {
+	explicit := name != ""
+	if !explicit {
+		name = defaultTemplate
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, errorf(ErrInvalid, "invalid template name: %v", name)
+	}
+
+	fp := filepath.Join(c.NetworkPath, templatesDir, name+mdExtension)
+	exist, err := fs.PathExists(fp)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		if explicit {
+			return nil, errorf(ErrNotFound, "template doesn't exist: %v", name)
+		}
+		return template.New(name).Parse(builtinTemplate)
+	}
+
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(name).Parse(string(b))
+}
+
+// parentLink returns a markdown link to the parent node
+func (c *Config) parentLink(parent string) (string, error) {
+	fp, err := c.notePath(parent)
+	if err != nil {
+		return "", err
+	}
+	fmFields, err := extractFrontMatterFields(fp)
+	if err != nil {
+		return "", nodeError(parent, err)
+	}
+	title := fmFields["title"]
+	if title == "" {
+		title = parent
+	}
+	return "[" + title + "](" + parent + ")", nil
+}
@@ -0,0 +1,154 @@
+// Package client is a client of the api. The methods of Client are
+// generated from openapi.Operations, the operations of the spec, by
+// go generate.
+package client
+
+//go:generate go run ../../cmd/zhuyi-client-gen -out client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// maxErrorBody is the most of an error response which is read
+const maxErrorBody = 1 << 20
+
+type Client struct {
+	// BaseURL of the api, e.g. https://notes.example.com
+	BaseURL string
+	// Network is the network of the network operations,
+	// the default network if empty
+	Network string
+	// Key is sent as a bearer token if set
+	Key string
+	// HTTPClient sends the requests, http.DefaultClient if nil. A
+	// client with a transport dialing the unix socket of the service
+	// talks to it over the socket.
+	HTTPClient *http.Client
+}
+
+func New(baseURL, key string) *Client {
+	return &Client{BaseURL: baseURL, Key: key}
+}
+
+// Error is an error answered by the api
+type Error struct {
+	Status int
+	// Code is the machine readable code of the error, see payloads.Failure
+	Code    string
+	Message string
+	// RetryAfter is how long to wait before trying again
+	// when rate limited, 0 if the api didn't tell
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d: %v", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%d %v: %v", e.Status, e.Code, e.Message)
+}
+
+// networkPath is the path of a network operation in the network of c
+func (c *Client) networkPath(path string) string {
+	if c.Network == "" {
+		return path
+	}
+	return "/n/" + url.PathEscape(c.Network) + path
+}
+
+// do sends a request, returning an *Error if it isn't answered with 200
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Key)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+func readError(resp *http.Response) error {
+	e := &Error{Status: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		e.Message = err.Error()
+		return e
+	}
+	var f payloads.Failure
+	if err := json.Unmarshal(b, &f); err == nil && f.Error != nil {
+		e.Code, e.Message = f.Code, *f.Error
+		return e
+	}
+	e.Message = strings.TrimSpace(string(b))
+	return e
+}
+
+// doJSON sends req, if not nil, as JSON and decodes the response into resp
+func (c *Client) doJSON(ctx context.Context, method, path string, q url.Values, req, resp interface{}) error {
+	var body io.Reader
+	contentType := ""
+	if req != nil {
+		b, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(b), "application/json"
+	}
+	return c.doDecode(ctx, method, path, q, contentType, body, resp)
+}
+
+// doDecode sends body, if not nil, and decodes the JSON response into resp
+func (c *Client) doDecode(ctx context.Context, method, path string, q url.Values, contentType string, body io.Reader, resp interface{}) error {
+	r, err := c.do(ctx, method, path, q, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(resp)
+}
+
+// doRaw sends body, if not nil, returning the body of the response,
+// which the caller has to close
+func (c *Client) doRaw(ctx context.Context, method, path string, q url.Values, contentType string, body io.Reader) (io.ReadCloser, error) {
+	r, err := c.do(ctx, method, path, q, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return r.Body, nil
+}
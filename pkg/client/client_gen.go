@@ -0,0 +1,286 @@
+// Code generated by zhuyi-client-gen. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// Status tells that the api is up, GET /status
+func (c *Client) Status(ctx context.Context) (*payloads.StatusResponse, error) {
+	path := "/status"
+	q := url.Values{}
+	var resp payloads.StatusResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Healthz checks that every network can be written to, 503 if not, GET /healthz
+func (c *Client) Healthz(ctx context.Context) (*payloads.HealthResponse, error) {
+	path := "/healthz"
+	q := url.Values{}
+	var resp payloads.HealthResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Readyz checks that every network can be written to and is warmed up, 503 if not, GET /readyz
+func (c *Client) Readyz(ctx context.Context) (*payloads.HealthResponse, error) {
+	path := "/readyz"
+	q := url.Values{}
+	var resp payloads.HealthResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// OpenAPI returns this spec, GET /openapi.json
+func (c *Client) OpenAPI(ctx context.Context) (io.ReadCloser, error) {
+	path := "/openapi.json"
+	q := url.Values{}
+	return c.doRaw(ctx, "GET", path, q, "", nil)
+}
+
+// Metrics returns the metrics in the Prometheus text format, GET /metrics
+func (c *Client) Metrics(ctx context.Context) (io.ReadCloser, error) {
+	path := "/metrics"
+	q := url.Values{}
+	return c.doRaw(ctx, "GET", path, q, "", nil)
+}
+
+// Networks lists the networks the key can use, GET /networks
+func (c *Client) Networks(ctx context.Context) (*payloads.NetworksResponse, error) {
+	path := "/networks"
+	q := url.Values{}
+	var resp payloads.NetworksResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// D3Graph returns the graph of the network for d3.js, GET /d3/graph
+func (c *Client) D3Graph(ctx context.Context) (*payloads.GraphResponse, error) {
+	path := c.networkPath("/d3/graph")
+	q := url.Values{}
+	var resp payloads.GraphResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExportGraphParams are the query parameters of ExportGraph
+type ExportGraphParams struct {
+	// format of the graph, graphml if not given
+	Format string
+}
+
+// ExportGraph exports the graph of the network, GET /graph
+func (c *Client) ExportGraph(ctx context.Context, p ExportGraphParams) (io.ReadCloser, error) {
+	path := c.networkPath("/graph")
+	q := url.Values{}
+	if p.Format != "" {
+		q.Set("format", p.Format)
+	}
+	return c.doRaw(ctx, "GET", path, q, "", nil)
+}
+
+// Export exports every node as NDJSON, a network.ExportedNode per line, GET /export
+func (c *Client) Export(ctx context.Context) (io.ReadCloser, error) {
+	path := c.networkPath("/export")
+	q := url.Values{}
+	return c.doRaw(ctx, "GET", path, q, "", nil)
+}
+
+// ImportParams are the query parameters of Import
+type ImportParams struct {
+	// what to do with nodes which already exist, skip if not given, overwrite needs the delete scope
+	Conflict string
+	// only tell what would be done
+	DryRun bool
+}
+
+// Import imports the nodes of an NDJSON export, POST /import
+func (c *Client) Import(ctx context.Context, p ImportParams, body io.Reader) (*payloads.ImportResponse, error) {
+	path := c.networkPath("/import")
+	q := url.Values{}
+	if p.Conflict != "" {
+		q.Set("conflict", p.Conflict)
+	}
+	if p.DryRun {
+		q.Set("dry-run", "true")
+	}
+	var resp payloads.ImportResponse
+	if err := c.doDecode(ctx, "POST", path, q, "application/x-ndjson", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Unlinked lists the nodes no other node links to, GET /unlinked
+func (c *Client) Unlinked(ctx context.Context) (*payloads.UnlinkedResponse, error) {
+	path := c.networkPath("/unlinked")
+	q := url.Values{}
+	var resp payloads.UnlinkedResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Reachability lists the roots every node can be reached from, GET /reachability
+func (c *Client) Reachability(ctx context.Context) (*payloads.ReachabilityResponse, error) {
+	path := c.networkPath("/reachability")
+	q := url.Values{}
+	var resp payloads.ReachabilityResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Diagnostics finds cycles, dead ends and the depths of the network, GET /diagnostics
+func (c *Client) Diagnostics(ctx context.Context) (*payloads.DiagnosticsResponse, error) {
+	path := c.networkPath("/diagnostics")
+	q := url.Values{}
+	var resp payloads.DiagnosticsResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AddNode creates a node, POST /node/add
+func (c *Client) AddNode(ctx context.Context, req payloads.AppendRequest) (*payloads.AppendResponse, error) {
+	path := c.networkPath("/node/add")
+	q := url.Values{}
+	var resp payloads.AppendResponse
+	if err := c.doJSON(ctx, "POST", path, q, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DelNode deletes a node, needs the delete scope, POST /node/del
+func (c *Client) DelNode(ctx context.Context, req payloads.DelRequest) (*payloads.DelResponse, error) {
+	path := c.networkPath("/node/del")
+	q := url.Values{}
+	var resp payloads.DelResponse
+	if err := c.doJSON(ctx, "POST", path, q, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// NodeParams are the query parameters of Node
+type NodeParams struct {
+	// replace embeds by what they embed
+	Expand bool
+	// how deep embeds are expanded
+	Depth int
+}
+
+// Node returns a node, GET /node/{file}
+func (c *Client) Node(ctx context.Context, file string, p NodeParams) (*payloads.NodeResponse, error) {
+	path := c.networkPath("/node/" + url.PathEscape(file))
+	q := url.Values{}
+	if p.Expand {
+		q.Set("expand", "true")
+	}
+	if p.Depth != 0 {
+		q.Set("depth", strconv.Itoa(p.Depth))
+	}
+	var resp payloads.NodeResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AppendNode appends text to a node, under a heading if given, POST /node/{file}/append
+func (c *Client) AppendNode(ctx context.Context, file string, req payloads.NodeAppendRequest) (*payloads.AppendResponse, error) {
+	path := c.networkPath("/node/" + url.PathEscape(file) + "/append")
+	q := url.Values{}
+	var resp payloads.AppendResponse
+	if err := c.doJSON(ctx, "POST", path, q, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Outline returns the heading tree of a node, GET /node/{file}/outline
+func (c *Client) Outline(ctx context.Context, file string) (*payloads.OutlineResponse, error) {
+	path := c.networkPath("/node/" + url.PathEscape(file) + "/outline")
+	q := url.Values{}
+	var resp payloads.OutlineResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Journal returns the journal node of a day, creating it if needed, needs the write scope, GET /journal/{date}
+func (c *Client) Journal(ctx context.Context, date string) (*payloads.NodeResponse, error) {
+	path := c.networkPath("/journal/" + url.PathEscape(date))
+	q := url.Values{}
+	var resp payloads.NodeResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AppendJournal appends an entry to the journal of a day, creating it if needed, POST /journal/{date}
+func (c *Client) AppendJournal(ctx context.Context, date string, req payloads.JournalRequest) (*payloads.AppendResponse, error) {
+	path := c.networkPath("/journal/" + url.PathEscape(date))
+	q := url.Values{}
+	var resp payloads.AppendResponse
+	if err := c.doJSON(ctx, "POST", path, q, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// BrokenAnchors lists the links to headings which don't exist, GET /anchors/broken
+func (c *Client) BrokenAnchors(ctx context.Context) (*payloads.BrokenAnchorsResponse, error) {
+	path := c.networkPath("/anchors/broken")
+	q := url.Values{}
+	var resp payloads.BrokenAnchorsResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnlinkedMentions lists the titles of nodes mentioned without a link, GET /mentions/unlinked
+func (c *Client) UnlinkedMentions(ctx context.Context) (*payloads.UnlinkedMentionsResponse, error) {
+	path := c.networkPath("/mentions/unlinked")
+	q := url.Values{}
+	var resp payloads.UnlinkedMentionsResponse
+	if err := c.doJSON(ctx, "GET", path, q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LinkMention turns an unlinked mention into a link, POST /mentions/link
+func (c *Client) LinkMention(ctx context.Context, req payloads.LinkMentionRequest) (*payloads.LinkMentionResponse, error) {
+	path := c.networkPath("/mentions/link")
+	q := url.Values{}
+	var resp payloads.LinkMentionResponse
+	if err := c.doJSON(ctx, "POST", path, q, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,721 @@
+// Package config loads the configuration of zhuyi from a TOML or YAML
+// file, the environment and flags, each overriding the one before.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// ZHUYI_CONFIG is the config file read when -config isn't given
+const ZHUYI_CONFIG = "ZHUYI_CONFIG"
+
+// NETWORKS adds networks as a comma separated list of name=path
+const NETWORKS = "NETWORKS"
+
+// DefaultNetwork is the name of the network in network_path,
+// which is also served without the /n/{network} prefix
+const DefaultNetwork = "default"
+
+type Config struct {
+	NetworkPath    string
+	Listen         string
+	TLS            TLS
+	Unix           Unix
+	Auth           Auth
+	CORS           CORS
+	RootNotes      []string
+	SelfLoops      string
+	NamingStrategy string
+	Cache          Cache
+	Timeouts       Timeouts
+	Limits         Limits
+	RateLimit      RateLimit
+	Log            Log
+	// Networks are served next to the one in NetworkPath,
+	// see AllNetworks
+	Networks []Network
+}
+
+// Network is a named network, configured in [networks.<name>]. The
+// options it leaves out are those of the top level.
+type Network struct {
+	Name           string
+	NetworkPath    string
+	RootNotes      []string
+	SelfLoops      string
+	NamingStrategy string
+	MaxNoteSize    int
+}
+
+// TLS is served on Listen when both Cert and Key are set
+type TLS struct {
+	Cert string
+	Key  string
+	// Reload loads the certificate again when its files
+	// change, e.g. when it's renewed, without a restart
+	Reload bool
+}
+
+// Unix is a unix domain socket served next to Listen, for local clients
+type Unix struct {
+	// Path of the socket, none if empty
+	Path string
+	// Mode is the octal permissions of the socket, e.g. 0660
+	Mode string
+}
+
+type Auth struct {
+	// Tokens are static bearer tokens, granted every scope
+	Tokens []string
+	// KeysFile holds hashed api keys with their scopes
+	KeysFile string
+}
+
+type CORS struct {
+	Origins     []string
+	Methods     []string
+	Headers     []string
+	Credentials bool
+	// MaxAge is in seconds, 0 leaves it out
+	MaxAge int
+}
+
+type Cache struct {
+	// Enabled keeps the parsed nodes in memory,
+	// reparsing only the files which changed
+	Enabled bool
+	// MaxNodes caps how many nodes are kept, 0 for no limit
+	MaxNodes int
+}
+
+// Timeouts of the http server, 0 for none
+type Timeouts struct {
+	// Read is how long reading a request, body included, may take
+	Read time.Duration
+	// Write is how long handling a request and writing its response may take
+	Write time.Duration
+	// Idle is how long keep-alive connections are kept open between requests
+	Idle time.Duration
+	// Drain is how long /readyz answers 503 on SIGTERM before the
+	// server stops accepting connections, so load balancers notice
+	Drain time.Duration
+	// Shutdown is how long requests in flight get to finish on SIGTERM
+	Shutdown time.Duration
+}
+
+// Limits on the size of what clients send, 0 for none
+type Limits struct {
+	// MaxBodySize is the most bytes of a request body
+	MaxBodySize int
+	// MaxNoteSize is the most bytes a node may have
+	// after it's created, appended to or imported
+	MaxNoteSize int
+}
+
+// RateLimit is how many requests every client, a key or else an
+// address, may make to the routes of each class, e.g. 120/m, see
+// ParseRate. A class left empty isn't limited.
+type RateLimit struct {
+	// Read are the GET routes
+	Read string
+	// Write are the other routes
+	Write string
+	// Bulk are import and export, which move whole networks
+	Bulk string
+}
+
+// Rate is N requests per Per, of which all N may be made at once
+type Rate struct {
+	N   int
+	Per time.Duration
+}
+
+// ParseRate parses a rate, N/unit with unit s, m, h or a duration,
+// e.g. 120/m or 10/30s. An empty rate is the zero Rate, no limit.
+func ParseRate(s string) (Rate, error) {
+	if s == "" {
+		return Rate{}, nil
+	}
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return Rate{}, fmt.Errorf("invalid rate: %v, expected requests/unit, e.g. 120/m", s)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate: %v, expected a positive number of requests", s)
+	}
+	unit := s[i+1:]
+	switch unit {
+	case "s", "m", "h":
+		unit = "1" + unit
+	}
+	per, err := time.ParseDuration(unit)
+	if err != nil || per <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate: %v, expected s, m, h or a duration after /", s)
+	}
+	return Rate{N: n, Per: per}, nil
+}
+
+// FileMode returns the permissions of the socket
+func (u Unix) FileMode() (os.FileMode, error) {
+	m, err := strconv.ParseUint(u.Mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid unix.mode: %v, expected octal permissions, e.g. 0660", u.Mode)
+	}
+	return os.FileMode(m), nil
+}
+
+type Log struct {
+	// Level is the lowest level logged, debug, info, warn or error
+	Level string
+	// Format is logfmt or json
+	Format string
+}
+
+// Default returns the configuration used for whatever isn't configured
+func Default() *Config {
+	return &Config{
+		Listen: "localhost:8080",
+		Unix: Unix{
+			Mode: "0600",
+		},
+		CORS: CORS{
+			Origins: []string{"*"},
+			Methods: []string{"GET", "POST", "PUT", "DELETE"},
+			Headers: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-API-Key"},
+		},
+		SelfLoops:      "flag",
+		NamingStrategy: "timestamp",
+		Log: Log{
+			Level:  "info",
+			Format: log.FormatLogfmt,
+		},
+		Timeouts: Timeouts{
+			Read:     15 * time.Second,
+			Write:    60 * time.Second,
+			Idle:     2 * time.Minute,
+			Shutdown: 30 * time.Second,
+		},
+		Limits: Limits{
+			MaxBodySize: 32 << 20,
+			MaxNoteSize: 1 << 20,
+		},
+		RateLimit: RateLimit{
+			Read:  "600/m",
+			Write: "120/m",
+			Bulk:  "10/m",
+		},
+	}
+}
+
+// option is a setting, named key in the file (section.name),
+// env in the environment and flag on the command line.
+type option struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  func(c *Config) interface{}
+}
+
+var options = []option{
+	{key: "network_path", env: "NETWORK_PATH", usage: "directory of the network",
+		value: func(c *Config) interface{} { return &c.NetworkPath }},
+	{key: "listen", env: "LISTEN_ADDR", usage: "address to listen on",
+		value: func(c *Config) interface{} { return &c.Listen }},
+	{key: "tls.cert", env: "TLS_CERT", usage: "tls certificate file",
+		value: func(c *Config) interface{} { return &c.TLS.Cert }},
+	{key: "tls.key", env: "TLS_KEY", usage: "tls private key file",
+		value: func(c *Config) interface{} { return &c.TLS.Key }},
+	{key: "tls.reload", env: "TLS_RELOAD", usage: "reload the tls certificate when its files change",
+		value: func(c *Config) interface{} { return &c.TLS.Reload }},
+	{key: "unix.path", env: "UNIX_SOCKET", usage: "unix socket to listen on as well",
+		value: func(c *Config) interface{} { return &c.Unix.Path }},
+	{key: "unix.mode", env: "UNIX_SOCKET_MODE", usage: "octal permissions of the unix socket",
+		value: func(c *Config) interface{} { return &c.Unix.Mode }},
+	{key: "auth.tokens", env: "AUTH_TOKENS", usage: "comma separated static bearer tokens", secret: true,
+		value: func(c *Config) interface{} { return &c.Auth.Tokens }},
+	{key: "auth.keys_file", env: "AUTH_KEYS_FILE", usage: "file of hashed api keys and their scopes",
+		value: func(c *Config) interface{} { return &c.Auth.KeysFile }},
+	{key: "cors.origins", env: "CORS_ORIGINS", usage: "comma separated origins allowed, * for any",
+		value: func(c *Config) interface{} { return &c.CORS.Origins }},
+	{key: "cors.methods", env: "CORS_METHODS", usage: "comma separated methods allowed",
+		value: func(c *Config) interface{} { return &c.CORS.Methods }},
+	{key: "cors.headers", env: "CORS_HEADERS", usage: "comma separated request headers allowed",
+		value: func(c *Config) interface{} { return &c.CORS.Headers }},
+	{key: "cors.credentials", env: "CORS_CREDENTIALS", usage: "allow credentials",
+		value: func(c *Config) interface{} { return &c.CORS.Credentials }},
+	{key: "cors.max_age", env: "CORS_MAX_AGE", usage: "seconds browsers may cache preflights",
+		value: func(c *Config) interface{} { return &c.CORS.MaxAge }},
+	{key: "root_notes", env: "ROOT_NOTES", usage: "comma separated nodes the network is walked from",
+		value: func(c *Config) interface{} { return &c.RootNotes }},
+	{key: "self_loops", env: "SELF_LOOPS", usage: "keep, flag or drop links of nodes to themselves",
+		value: func(c *Config) interface{} { return &c.SelfLoops }},
+	{key: "naming_strategy", env: "NAMING_STRATEGY", usage: "timestamp, ulid, uuid or slug",
+		value: func(c *Config) interface{} { return &c.NamingStrategy }},
+	{key: "cache.enabled", env: "CACHE_ENABLED", usage: "keep parsed nodes in memory",
+		value: func(c *Config) interface{} { return &c.Cache.Enabled }},
+	{key: "cache.max_nodes", env: "CACHE_MAX_NODES", usage: "most nodes kept in memory, 0 for no limit",
+		value: func(c *Config) interface{} { return &c.Cache.MaxNodes }},
+	{key: "log.level", env: "LOG_LEVEL", usage: "lowest level logged, debug, info, warn or error",
+		value: func(c *Config) interface{} { return &c.Log.Level }},
+	{key: "log.format", env: "LOG_FORMAT", usage: "logfmt or json",
+		value: func(c *Config) interface{} { return &c.Log.Format }},
+	{key: "timeouts.read", env: "READ_TIMEOUT", usage: "longest time reading a request may take, e.g. 15s",
+		value: func(c *Config) interface{} { return &c.Timeouts.Read }},
+	{key: "timeouts.write", env: "WRITE_TIMEOUT", usage: "longest time handling a request may take, e.g. 1m",
+		value: func(c *Config) interface{} { return &c.Timeouts.Write }},
+	{key: "timeouts.idle", env: "IDLE_TIMEOUT", usage: "how long idle keep-alive connections are kept open",
+		value: func(c *Config) interface{} { return &c.Timeouts.Idle }},
+	{key: "timeouts.drain", env: "DRAIN_DELAY", usage: "how long /readyz answers 503 on shutdown before connections are refused, e.g. 10s",
+		value: func(c *Config) interface{} { return &c.Timeouts.Drain }},
+	{key: "timeouts.shutdown", env: "SHUTDOWN_TIMEOUT", usage: "how long requests in flight get to finish on shutdown",
+		value: func(c *Config) interface{} { return &c.Timeouts.Shutdown }},
+	{key: "limits.max_body_size", env: "MAX_BODY_SIZE", usage: "most bytes of a request body, 0 for no limit",
+		value: func(c *Config) interface{} { return &c.Limits.MaxBodySize }},
+	{key: "limits.max_note_size", env: "MAX_NOTE_SIZE", usage: "most bytes a node may have, 0 for no limit",
+		value: func(c *Config) interface{} { return &c.Limits.MaxNoteSize }},
+	{key: "rate_limit.read", env: "RATE_LIMIT_READ", usage: "requests a client may make to GET routes, e.g. 600/m, empty for no limit",
+		value: func(c *Config) interface{} { return &c.RateLimit.Read }},
+	{key: "rate_limit.write", env: "RATE_LIMIT_WRITE", usage: "requests a client may make to the other routes, e.g. 120/m",
+		value: func(c *Config) interface{} { return &c.RateLimit.Write }},
+	{key: "rate_limit.bulk", env: "RATE_LIMIT_BULK", usage: "imports and exports a client may make, e.g. 10/m",
+		value: func(c *Config) interface{} { return &c.RateLimit.Bulk }},
+}
+
+func (o option) flag() string {
+	return strings.Replace(strings.Replace(o.key, ".", "-", -1), "_", "-", -1)
+}
+
+// networkOptions are the options of [networks.<name>]
+var networkOptions = []struct {
+	key   string
+	value func(n *Network) interface{}
+}{
+	{"network_path", func(n *Network) interface{} { return &n.NetworkPath }},
+	{"root_notes", func(n *Network) interface{} { return &n.RootNotes }},
+	{"self_loops", func(n *Network) interface{} { return &n.SelfLoops }},
+	{"naming_strategy", func(n *Network) interface{} { return &n.NamingStrategy }},
+	{"max_note_size", func(n *Network) interface{} { return &n.MaxNoteSize }},
+}
+
+// networkNameExtractor matches the names networks can have
+var networkNameExtractor = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func (o option) set(c *Config, v value) error {
+	return setValue(o.key, o.value(c), v)
+}
+
+// setValue sets the option key from a file, env or flag value,
+// lists are either given as such or comma separated
+func setValue(key string, ptr interface{}, v value) error {
+	switch p := ptr.(type) {
+	case *string:
+		if v.isList {
+			return fmt.Errorf("%v: expected a string, not a list", key)
+		}
+		*p = v.s
+	case *[]string:
+		if v.isList {
+			*p = v.list
+		} else {
+			*p = splitList(v.s)
+		}
+	case *bool:
+		b, err := strconv.ParseBool(v.s)
+		if err != nil || v.isList {
+			return fmt.Errorf("%v: expected true or false, got %v", key, v)
+		}
+		*p = b
+	case *int:
+		i, err := strconv.Atoi(v.s)
+		if err != nil || v.isList {
+			return fmt.Errorf("%v: expected a number, got %v", key, v)
+		}
+		*p = i
+	case *time.Duration:
+		d, err := time.ParseDuration(v.s)
+		if err != nil || v.isList {
+			return fmt.Errorf("%v: expected a duration, e.g. 30s, got %v", key, v)
+		}
+		*p = d
+	}
+	return nil
+}
+
+// Load reads the config file, given by -config or ZHUYI_CONFIG, and
+// overrides it with the environment and then the flags in args. The
+// options are added to fs as flags, a nil fs skips the flags.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	file := os.Getenv(ZHUYI_CONFIG)
+	flags := make(map[string]*string)
+	var networks *string
+	if fs != nil {
+		fs.StringVar(&file, "config", file, "config file, toml or yaml (env "+ZHUYI_CONFIG+")")
+		networks = fs.String("networks", "", "comma separated networks to serve, name=path (env "+NETWORKS+")")
+		for _, o := range options {
+			flags[o.key] = fs.String(o.flag(), "", o.usage+" (env "+o.env+")")
+		}
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+	}
+
+	c := Default()
+
+	if file != "" {
+		if err := c.readFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, o := range options {
+		if v, ok := os.LookupEnv(o.env); ok {
+			if err := o.set(c, value{s: v}); err != nil {
+				return nil, fmt.Errorf("%v: %v", o.env, err)
+			}
+		}
+	}
+	if v, ok := os.LookupEnv(NETWORKS); ok {
+		if err := c.setNetworkPaths(v); err != nil {
+			return nil, fmt.Errorf("%v: %v", NETWORKS, err)
+		}
+	}
+
+	if fs != nil {
+		var err error
+		fs.Visit(func(f *flag.Flag) {
+			for _, o := range options {
+				if err == nil && f.Name == o.flag() {
+					err = o.set(c, value{s: *flags[o.key]})
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if *networks != "" {
+			if err := c.setNetworkPaths(*networks); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) readFile(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]value
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".toml":
+		values, err = parseTOML(string(b))
+	case ".yaml", ".yml":
+		values, err = parseYAML(string(b))
+	default:
+		return fmt.Errorf("%v: unknown config format, expected .toml, .yaml or .yml", file)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+
+	known := make(map[string]option, len(options))
+	for _, o := range options {
+		known[o.key] = o
+	}
+	for k, v := range values {
+		if strings.HasPrefix(k, "networks.") {
+			if err := c.setNetworkOption(strings.TrimPrefix(k, "networks."), v); err != nil {
+				return fmt.Errorf("%v: %v", file, err)
+			}
+			continue
+		}
+		o, exists := known[k]
+		if !exists {
+			return fmt.Errorf("%v: unknown option: %v", file, k)
+		}
+		if err := o.set(c, v); err != nil {
+			return fmt.Errorf("%v: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// network returns the network called name, adding it if there's none
+func (c *Config) network(name string) *Network {
+	for i := range c.Networks {
+		if c.Networks[i].Name == name {
+			return &c.Networks[i]
+		}
+	}
+	c.Networks = append(c.Networks, Network{Name: name})
+	return &c.Networks[len(c.Networks)-1]
+}
+
+// setNetworkOption sets name.option of a network
+func (c *Config) setNetworkOption(key string, v value) error {
+	i := strings.Index(key, ".")
+	if i < 0 {
+		return fmt.Errorf("unknown option: networks.%v", key)
+	}
+	name, opt := key[:i], key[i+1:]
+	for _, o := range networkOptions {
+		if o.key == opt {
+			return setValue("networks."+key, o.value(c.network(name)), v)
+		}
+	}
+	return fmt.Errorf("unknown option: networks.%v", key)
+}
+
+// setNetworkPaths sets the paths of networks from name=path,...
+func (c *Config) setNetworkPaths(s string) error {
+	for _, e := range splitList(s) {
+		i := strings.Index(e, "=")
+		if i <= 0 {
+			return fmt.Errorf("expected name=path, got %v", e)
+		}
+		c.network(strings.TrimSpace(e[:i])).NetworkPath = strings.TrimSpace(e[i+1:])
+	}
+	return nil
+}
+
+// AllNetworks returns the networks served, the default network in
+// NetworkPath first followed by the others sorted by name, with the
+// options they leave out set to those of the top level.
+func (c *Config) AllNetworks() []Network {
+	ns := make([]Network, 0, len(c.Networks)+1)
+	if c.NetworkPath != "" {
+		ns = append(ns, Network{Name: DefaultNetwork, NetworkPath: c.NetworkPath})
+	}
+	named := make([]Network, len(c.Networks))
+	copy(named, c.Networks)
+	sort.Slice(named, func(i, j int) bool {
+		return named[i].Name < named[j].Name
+	})
+	ns = append(ns, named...)
+
+	for i := range ns {
+		n := &ns[i]
+		if n.RootNotes == nil {
+			n.RootNotes = c.RootNotes
+		}
+		if n.SelfLoops == "" {
+			n.SelfLoops = c.SelfLoops
+		}
+		if n.NamingStrategy == "" {
+			n.NamingStrategy = c.NamingStrategy
+		}
+		if n.MaxNoteSize == 0 {
+			n.MaxNoteSize = c.Limits.MaxNoteSize
+		}
+	}
+	return ns
+}
+
+// Network returns the network called name, the
+// default one, or else the first, if name is empty
+func (c *Config) Network(name string) (Network, error) {
+	ns := c.AllNetworks()
+	if name == "" && len(ns) > 0 {
+		return ns[0], nil
+	}
+	for _, n := range ns {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return Network{}, fmt.Errorf("no such network: %v", name)
+}
+
+// Validate checks the options which don't depend on anything else,
+// the network checks its own when it's set up.
+func (c *Config) Validate() error {
+	if c.NetworkPath == "" && len(c.Networks) == 0 {
+		return fmt.Errorf("neither network_path nor any networks are set")
+	}
+	for _, n := range c.Networks {
+		if !networkNameExtractor.MatchString(n.Name) {
+			return fmt.Errorf("invalid network name: %v, expected lower case letters, digits, - and _", n.Name)
+		}
+		if n.Name == DefaultNetwork && c.NetworkPath != "" {
+			return fmt.Errorf("network %v is the one in network_path", DefaultNetwork)
+		}
+		if n.NetworkPath == "" {
+			return fmt.Errorf("networks.%v.network_path is not set", n.Name)
+		}
+		if n.MaxNoteSize < 0 {
+			return fmt.Errorf("networks.%v.max_note_size is negative: %d", n.Name, n.MaxNoteSize)
+		}
+	}
+	if c.Listen == "" && c.Unix.Path == "" {
+		return fmt.Errorf("neither listen nor unix.path is set")
+	}
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		return fmt.Errorf("tls.cert and tls.key have to be set together")
+	}
+	if c.TLS.Cert != "" && c.Listen == "" {
+		return fmt.Errorf("tls is served on listen, which is not set")
+	}
+	if _, err := c.Unix.FileMode(); err != nil {
+		return err
+	}
+	for _, f := range []string{c.TLS.Cert, c.TLS.Key, c.Auth.KeysFile} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return err
+		}
+	}
+	if len(c.CORS.Methods) == 0 {
+		return fmt.Errorf("cors.methods is empty")
+	}
+	if c.CORS.MaxAge < 0 {
+		return fmt.Errorf("cors.max_age is negative: %d", c.CORS.MaxAge)
+	}
+	if c.CORS.Credentials {
+		for _, o := range c.CORS.Origins {
+			if o == "*" {
+				return fmt.Errorf("cors.credentials can't be set with the * origin, which would let any site use them: list the origins instead")
+			}
+		}
+	}
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		return err
+	}
+	if err := log.ValidFormat(c.Log.Format); err != nil {
+		return err
+	}
+	if c.Cache.MaxNodes < 0 {
+		return fmt.Errorf("cache.max_nodes is negative: %d", c.Cache.MaxNodes)
+	}
+	if c.Limits.MaxBodySize < 0 {
+		return fmt.Errorf("limits.max_body_size is negative: %d", c.Limits.MaxBodySize)
+	}
+	if c.Limits.MaxNoteSize < 0 {
+		return fmt.Errorf("limits.max_note_size is negative: %d", c.Limits.MaxNoteSize)
+	}
+	for key, r := range map[string]string{
+		"rate_limit.read":  c.RateLimit.Read,
+		"rate_limit.write": c.RateLimit.Write,
+		"rate_limit.bulk":  c.RateLimit.Bulk,
+	} {
+		if _, err := ParseRate(r); err != nil {
+			return fmt.Errorf("%v: %v", key, err)
+		}
+	}
+	for _, t := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"timeouts.read", c.Timeouts.Read},
+		{"timeouts.write", c.Timeouts.Write},
+		{"timeouts.idle", c.Timeouts.Idle},
+		{"timeouts.drain", c.Timeouts.Drain},
+		{"timeouts.shutdown", c.Timeouts.Shutdown},
+	} {
+		if t.d < 0 {
+			return fmt.Errorf("%v is negative: %v", t.key, t.d)
+		}
+	}
+	return nil
+}
+
+// Print writes the configuration as TOML, with secrets left out
+func (c *Config) Print(w io.Writer) error {
+	sections := make(map[string][]option)
+	for _, o := range options {
+		section := ""
+		if i := strings.Index(o.key, "."); i >= 0 {
+			section = o.key[:i]
+		}
+		sections[section] = append(sections[section], o)
+	}
+	names := make([]string, 0, len(sections))
+	for s := range sections {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, s := range names {
+		if s != "" {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "[%s]\n", s)
+		}
+		for _, o := range sections[s] {
+			fmt.Fprintf(&b, "%s = %s\n", strings.TrimPrefix(o.key, s+"."), o.format(c))
+		}
+	}
+	named := c.AllNetworks()
+	if c.NetworkPath != "" {
+		named = named[1:]
+	}
+	for _, n := range named {
+		fmt.Fprintf(&b, "\n[networks.%s]\n", n.Name)
+		for _, o := range networkOptions {
+			fmt.Fprintf(&b, "%s = %s\n", o.key, formatValue(o.value(&n), false))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (o option) format(c *Config) string {
+	return formatValue(o.value(c), o.secret)
+}
+
+func formatValue(ptr interface{}, secret bool) string {
+	switch p := ptr.(type) {
+	case *string:
+		return strconv.Quote(*p)
+	case *[]string:
+		l := make([]string, 0, len(*p))
+		for _, s := range *p {
+			if secret {
+				s = "********"
+			}
+			l = append(l, strconv.Quote(s))
+		}
+		return "[" + strings.Join(l, ", ") + "]"
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *int:
+		return strconv.Itoa(*p)
+	case *time.Duration:
+		return strconv.Quote(p.String())
+	}
+	return ""
+}
+
+func splitList(s string) []string {
+	l := make([]string, 0)
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCORS(t *testing.T) {
+	tests := []struct {
+		origins     []string
+		credentials bool
+		err         string
+	}{
+		{[]string{"*"}, false, ""},
+		{[]string{"https://notes.example.com"}, true, ""},
+		{[]string{"*"}, true, "cors.credentials can't be set with the * origin"},
+		{[]string{"https://notes.example.com", "*"}, true, "cors.credentials can't be set with the * origin"},
+	}
+
+	for _, tt := range tests {
+		c := Default()
+		c.NetworkPath = "/notes"
+		c.CORS.Origins = tt.origins
+		c.CORS.Credentials = tt.credentials
+
+		err := c.Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%v, credentials %v: %v", tt.origins, tt.credentials, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%v, credentials %v: err = %v, want %q", tt.origins, tt.credentials, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// value is a scalar or a list read from a config file
+type value struct {
+	s      string
+	list   []string
+	isList bool
+}
+
+func (v value) String() string {
+	if v.isList {
+		return "[" + strings.Join(v.list, ", ") + "]"
+	}
+	return v.s
+}
+
+// parseTOML parses the subset of TOML the config needs: tables of
+// keys with strings, booleans, integers and single line arrays.
+// Keys are returned as table.key.
+func parseTOML(s string) (map[string]value, error) {
+	values := make(map[string]value)
+	table := ""
+
+	for i, line := range strings.Split(s, "\n") {
+		l := strings.TrimSpace(stripComment(line))
+		if l == "" {
+			continue
+		}
+
+		if strings.HasPrefix(l, "[") {
+			if !strings.HasSuffix(l, "]") || strings.HasPrefix(l, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table: %v", i+1, l)
+			}
+			table = strings.TrimSpace(l[1 : len(l)-1])
+			continue
+		}
+
+		eq := strings.Index(l, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		k := strings.TrimSpace(l[:eq])
+		if table != "" {
+			k = table + "." + k
+		}
+		if _, exists := values[k]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key: %v", i+1, k)
+		}
+
+		v, err := parseValue(strings.TrimSpace(l[eq+1:]), true)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+// parseYAML parses the subset of YAML the config needs: nested maps
+// of scalars, flow lists, [a, b], and block lists. Keys are returned
+// joined by dots, map.key.
+func parseYAML(s string) (map[string]value, error) {
+	values := make(map[string]value)
+
+	type level struct {
+		indent int
+		key    string
+	}
+	// the maps the line is nested in
+	stack := make([]level, 0)
+	// the key a block list is being read for
+	listKey := ""
+
+	for i, line := range strings.Split(s, "\n") {
+		raw := strings.TrimRight(stripComment(line), " \t\r")
+		l := strings.TrimSpace(raw)
+		if l == "" || l == "---" {
+			continue
+		}
+		if strings.Contains(raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))], "\t") {
+			return nil, fmt.Errorf("line %d: tabs can't be used for indentation", i+1)
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+
+		if strings.HasPrefix(l, "- ") || l == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a list", i+1)
+			}
+			item, err := parseValue(strings.TrimSpace(strings.TrimPrefix(l, "-")), false)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			v := values[listKey]
+			v.list = append(v.list, item.s)
+			values[listKey] = v
+			continue
+		}
+		listKey = ""
+
+		colon := strings.Index(l, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected key: value", i+1)
+		}
+		k, rest := strings.TrimSpace(l[:colon]), strings.TrimSpace(l[colon+1:])
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 && indent > 0 {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+		if len(stack) > 0 {
+			k = stack[len(stack)-1].key + "." + k
+		}
+		if _, exists := values[k]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key: %v", i+1, k)
+		}
+
+		if rest == "" {
+			// a map, or a block list, follows
+			stack = append(stack, level{indent: indent, key: k})
+			listKey = k
+			values[k] = value{isList: true, list: []string{}}
+			continue
+		}
+
+		v, err := parseValue(rest, false)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		values[k] = v
+	}
+
+	// maps aren't values
+	for k, v := range values {
+		if v.isList && len(v.list) == 0 && hasPrefixKey(values, k+".") {
+			delete(values, k)
+		}
+	}
+	return values, nil
+}
+
+func hasPrefixKey(values map[string]value, prefix string) bool {
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseValue parses a scalar or a [list] of scalars. TOML requires
+// strings to be quoted, YAML doesn't.
+func parseValue(s string, quoted bool) (value, error) {
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return value{}, fmt.Errorf("unterminated list: %v", s)
+		}
+		v := value{isList: true, list: []string{}}
+		for _, e := range splitFlowList(s[1 : len(s)-1]) {
+			item, err := parseValue(e, quoted)
+			if err != nil {
+				return value{}, err
+			}
+			if item.isList {
+				return value{}, fmt.Errorf("nested lists aren't supported: %v", s)
+			}
+			v.list = append(v.list, item.s)
+		}
+		return v, nil
+	}
+
+	switch {
+	case strings.HasPrefix(s, `"`):
+		u, err := strconv.Unquote(s)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid string: %v", s)
+		}
+		return value{s: u}, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return value{}, fmt.Errorf("invalid string: %v", s)
+		}
+		return value{s: s[1 : len(s)-1]}, nil
+	}
+
+	if quoted && s != "true" && s != "false" {
+		if _, err := strconv.Atoi(s); err != nil {
+			return value{}, fmt.Errorf("strings have to be quoted: %v", s)
+		}
+	}
+	return value{s: s}, nil
+}
+
+// splitFlowList splits the items of a list on commas outside of quotes
+func splitFlowList(s string) []string {
+	items := make([]string, 0)
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || s[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, s[start:])
+
+	res := make([]string, 0, len(items))
+	for _, e := range items {
+		if e = strings.TrimSpace(e); e != "" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// stripComment removes a # comment which isn't inside quotes
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || line[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func scalar(s string) value {
+	return value{s: s}
+}
+
+func list(l ...string) value {
+	return value{isList: true, list: append([]string{}, l...)}
+}
+
+type parseTest struct {
+	name string
+	in   string
+	want map[string]value
+	// err is a part of the error, with the line number
+	err string
+}
+
+func runParseTests(t *testing.T, parse func(string) (map[string]value, error), tests []parseTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.in)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	runParseTests(t, parseTOML, []parseTest{
+		{name: "top level",
+			in: `
+listen = "localhost:8000" # a comment
+max_note_size = 1024
+`,
+			want: map[string]value{
+				"listen":        scalar("localhost:8000"),
+				"max_note_size": scalar("1024"),
+			}},
+		{name: "tables",
+			in: `
+[cache]
+enabled = true
+
+[networks.work]
+network_path = "/notes/work"
+root_notes = ["index.md", "todo.md"]
+
+[networks.home]
+network_path = '/notes/home'
+`,
+			want: map[string]value{
+				"cache.enabled":              scalar("true"),
+				"networks.work.network_path": scalar("/notes/work"),
+				"networks.work.root_notes":   list("index.md", "todo.md"),
+				"networks.home.network_path": scalar("/notes/home"),
+			}},
+		{name: "quoting",
+			in: `
+a = "with # hash"
+b = 'with # hash and "quotes"'
+c = "escaped \"quote\" # and hash" # comment
+d = "tab\tand\\backslash"
+e = ["a, b", 'c # d', ""]
+f = []
+`,
+			want: map[string]value{
+				"a": scalar("with # hash"),
+				"b": scalar(`with # hash and "quotes"`),
+				"c": scalar(`escaped "quote" # and hash`),
+				"d": scalar("tab\tand\\backslash"),
+				"e": list("a, b", "c # d", ""),
+				"f": list(),
+			}},
+		{name: "tab indentation",
+			in: "[log]\n\tlevel = \"debug\"\n",
+			want: map[string]value{
+				"log.level": scalar("debug"),
+			}},
+		{name: "duplicate key",
+			in:  "[networks.work]\nnetwork_path = \"/a\"\n\nnetwork_path = \"/b\"\n",
+			err: "line 4: duplicate key: networks.work.network_path"},
+		{name: "duplicate key in a table of its own",
+			in: "listen = \"a\"\n[networks.work]\nlisten = \"b\"\n",
+			want: map[string]value{
+				"listen":               scalar("a"),
+				"networks.work.listen": scalar("b"),
+			}},
+		{name: "unquoted string",
+			in:  "\nlisten = localhost\n",
+			err: "line 2: strings have to be quoted"},
+		{name: "array of tables",
+			in:  "[[networks]]\n",
+			err: "line 1: invalid table"},
+		{name: "no value",
+			in:  "a = 1\nb\n",
+			err: "line 2: expected key = value"},
+		{name: "unterminated list",
+			in:  "a = [\"x\"\n",
+			err: "line 1: unterminated list"},
+		{name: "nested list",
+			in:  "a = [[1], 2]\n",
+			err: "line 1: nested lists"},
+		{name: "unterminated string",
+			in:  "a = \"x\n",
+			err: "line 1: invalid string"},
+	})
+}
+
+func TestParseYAML(t *testing.T) {
+	runParseTests(t, parseYAML, []parseTest{
+		{name: "top level",
+			in: `---
+listen: localhost:8000 # a comment
+max_note_size: 1024
+`,
+			want: map[string]value{
+				"listen":        scalar("localhost:8000"),
+				"max_note_size": scalar("1024"),
+			}},
+		{name: "nested maps",
+			in: `
+cache:
+  enabled: true
+networks:
+  work:
+    network_path: /notes/work
+    root_notes: [index.md, todo.md]
+  home:
+    network_path: '/notes/home'
+log:
+  level: debug
+`,
+			want: map[string]value{
+				"cache.enabled":              scalar("true"),
+				"networks.work.network_path": scalar("/notes/work"),
+				"networks.work.root_notes":   list("index.md", "todo.md"),
+				"networks.home.network_path": scalar("/notes/home"),
+				"log.level":                  scalar("debug"),
+			}},
+		{name: "block lists",
+			in: `
+root_notes:
+  - index.md
+  - "with # hash"
+  -   'quoted, with comma'
+networks:
+  work:
+    root_notes:
+    - todo.md
+empty:
+`,
+			want: map[string]value{
+				"root_notes":               list("index.md", "with # hash", "quoted, with comma"),
+				"networks.work.root_notes": list("todo.md"),
+				"empty":                    list(),
+			}},
+		{name: "quoting",
+			in: `
+a: "with # hash"
+b: 'with # hash and "quotes"' # comment
+c: "escaped \"quote\" # and hash"
+d: plain # comment
+e: ["a, b", 'c # d', e]
+`,
+			want: map[string]value{
+				"a": scalar("with # hash"),
+				"b": scalar(`with # hash and "quotes"`),
+				"c": scalar(`escaped "quote" # and hash`),
+				"d": scalar("plain"),
+				"e": list("a, b", "c # d", "e"),
+			}},
+		{name: "tab indentation",
+			in:  "log:\n\tlevel: debug\n",
+			err: "line 2: tabs can't be used for indentation"},
+		{name: "tab after spaces",
+			in:  "log:\n  \tlevel: debug\n",
+			err: "line 2: tabs can't be used for indentation"},
+		{name: "duplicate key",
+			in:  "networks:\n  work:\n    network_path: /a\n    network_path: /b\n",
+			err: "line 4: duplicate key: networks.work.network_path"},
+		{name: "duplicate map",
+			in:  "log:\n  level: debug\nlisten: x\nlog:\n  format: json\n",
+			err: "line 4: duplicate key: log"},
+		{name: "list item outside of a list",
+			in:  "listen: x\n- a\n",
+			err: "line 2: list item outside of a list"},
+		{name: "unexpected indentation",
+			in:  "  listen: x\n",
+			err: "line 1: unexpected indentation"},
+		{name: "no value",
+			in:  "listen: x\njust text\n",
+			err: "line 2: expected key: value"},
+		{name: "unterminated list",
+			in:  "a: [x, y\n",
+			err: "line 1: unterminated list"},
+	})
+}
+
+// TestReadNetworks checks that both formats configure the same networks
+func TestReadNetworks(t *testing.T) {
+	files := map[string]string{
+		"config.toml": `
+network_path = "/notes"
+
+[networks.work]
+network_path = "/notes/work"
+root_notes = ["index.md", "todo.md"]
+max_note_size = 2048
+
+[networks.home]
+network_path = "/notes/home"
+self_loops = "allow"
+`,
+		"config.yaml": `
+network_path: /notes
+networks:
+  work:
+    network_path: /notes/work
+    root_notes:
+      - index.md
+      - todo.md
+    max_note_size: 2048
+  home:
+    network_path: /notes/home
+    self_loops: allow
+`,
+	}
+	want := []Network{
+		{Name: "home", NetworkPath: "/notes/home", SelfLoops: "allow"},
+		{Name: "work", NetworkPath: "/notes/work", RootNotes: []string{"index.md", "todo.md"}, MaxNoteSize: 2048},
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c := Default()
+			if err := c.readFile(file); err != nil {
+				t.Fatal(err)
+			}
+			if c.NetworkPath != "/notes" {
+				t.Errorf("network_path = %v, want /notes", c.NetworkPath)
+			}
+			sort.Slice(c.Networks, func(i, j int) bool {
+				return c.Networks[i].Name < c.Networks[j].Name
+			})
+			if !reflect.DeepEqual(c.Networks, want) {
+				t.Errorf("networks = %+v, want %+v", c.Networks, want)
+			}
+		})
+	}
+}
+
+func TestLoadFlagsOverrideFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := "network_path: /from/file\nlisten: localhost:1\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c, err := Load(fs, []string{"-config", file, "-network-path", "/from/flag", "rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NetworkPath != "/from/flag" {
+		t.Errorf("network_path = %v, want the one of the flag", c.NetworkPath)
+	}
+	if c.Listen != "localhost:1" {
+		t.Errorf("listen = %v, want the one of the file", c.Listen)
+	}
+	if args := fs.Args(); !reflect.DeepEqual(args, []string{"rest"}) {
+		t.Errorf("args = %v, want [rest]", args)
+	}
+}
@@ -1,16 +1,239 @@
+// Package log writes leveled, structured logs as logfmt or JSON. Fields
+// are given as key value pairs after the message:
+//
+//	log.Info("listening", "addr", addr)
+//
+// which is written as
+//
+//	time=2026-10-19T13:59:44.101Z level=info msg=listening addr=localhost:8080
 package log
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named s, debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(s, n) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("invalid log level: %v, expected %v", s, strings.Join(levelNames, ", "))
+}
+
+// Formats logs can be written in
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+// ValidFormat returns an error if format isn't logfmt or json
+func ValidFormat(format string) error {
+	switch format {
+	case FormatLogfmt, FormatJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid log format: %v, expected %v or %v", format, FormatLogfmt, FormatJSON)
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// output is where the loggers write, shared by every logger
+// derived from the same one so Configure applies to them all
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format string
+}
+
+// Logger writes logs with its fields added to every line
+type Logger struct {
+	out    *output
+	fields []interface{}
+}
+
+var std = &Logger{out: &output{w: os.Stderr, level: LevelInfo, format: FormatLogfmt}}
+
+// Configure sets where logs are written, the lowest level written and
+// the format, logfmt or json
+func Configure(w io.Writer, level, format string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if err := ValidFormat(format); err != nil {
+		return err
+	}
+	std.out.mu.Lock()
+	defer std.out.mu.Unlock()
+	std.out.w, std.out.level, std.out.format = w, l, format
+	return nil
+}
+
+// With returns a logger adding the key value pairs to every line
+func With(kv ...interface{}) *Logger {
+	return std.With(kv...)
+}
+
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+	return &Logger{out: l.out, fields: fields}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, see FromContext
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of ctx, the default one if it has none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return std
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) { l.log(LevelDebug, msg, kv) }
+func (l *Logger) Info(msg string, kv ...interface{})  { l.log(LevelInfo, msg, kv) }
+func (l *Logger) Warn(msg string, kv ...interface{})  { l.log(LevelWarn, msg, kv) }
+func (l *Logger) Error(msg string, kv ...interface{}) { l.log(LevelError, msg, kv) }
+
+func Debug(msg string, kv ...interface{}) { std.log(LevelDebug, msg, kv) }
+func Info(msg string, kv ...interface{})  { std.log(LevelInfo, msg, kv) }
+func Warn(msg string, kv ...interface{})  { std.log(LevelWarn, msg, kv) }
+func Error(msg string, kv ...interface{}) { std.log(LevelError, msg, kv) }
+
+// LogError logs err, if it isn't nil, with where it was logged from
 func LogError(err error) (b bool) {
 	if err != nil {
 		pc, fn, line, _ := runtime.Caller(1)
 
-		log.Printf("[error] [%s:%d] [%s] err: %v", fn, line, runtime.FuncForPC(pc).Name(), err)
+		std.log(LevelError, err.Error(), []interface{}{
+			"caller", fn + ":" + strconv.Itoa(line),
+			"func", runtime.FuncForPC(pc).Name(),
+		})
 		b = true
 	}
 	return
 }
+
+func (l *Logger) log(level Level, msg string, kv []interface{}) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	if level < l.out.level {
+		return
+	}
+
+	fields := make([]interface{}, 0, 6+len(l.fields)+len(kv))
+	fields = append(fields, "time", time.Now().UTC().Format(timeFormat), "level", level.String(), "msg", msg)
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+	if len(fields)%2 != 0 {
+		fields = append(fields, "(missing)")
+	}
+
+	var b bytes.Buffer
+	if l.out.format == FormatJSON {
+		writeJSON(&b, fields)
+	} else {
+		writeLogfmt(&b, fields)
+	}
+	b.WriteByte('\n')
+	l.out.w.Write(b.Bytes())
+}
+
+func writeLogfmt(b *bytes.Buffer, fields []interface{}) {
+	for i := 0; i < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(fmt.Sprint(fields[i]))
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(fields[i+1]))
+	}
+}
+
+// logfmtValue quotes values which would be ambiguous unquoted
+func logfmtValue(v interface{}) string {
+	s := stringValue(v)
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+func writeJSON(b *bytes.Buffer, fields []interface{}) {
+	b.WriteByte('{')
+	for i := 0; i < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(fmt.Sprint(fields[i]))
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(jsonValue(fields[i+1]))
+	}
+	b.WriteByte('}')
+}
+
+func jsonValue(v interface{}) []byte {
+	switch v.(type) {
+	case error, fmt.Stringer:
+		v = stringValue(v)
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		j, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return j
+}
+
+func stringValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case error:
+		return t.Error()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// configure has the logs written to the returned buffer until the test ends
+func configure(t *testing.T, level, format string) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Configure(&b, level, format); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Configure(os.Stderr, "info", FormatLogfmt)
+	})
+	return &b
+}
+
+var logfmtTime = regexp.MustCompile(`^time=\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z `)
+
+func TestLogfmt(t *testing.T) {
+	tests := []struct {
+		kv   []interface{}
+		want string
+	}{
+		{[]interface{}{"addr", "localhost:8080"}, "addr=localhost:8080"},
+		{[]interface{}{"empty", ""}, `empty=""`},
+		{[]interface{}{"space", "a b"}, `space="a b"`},
+		{[]interface{}{"equals", "a=b"}, `equals="a=b"`},
+		{[]interface{}{"quote", `say "hi"`}, `quote="say \"hi\""`},
+		{[]interface{}{"newline", "a\nb"}, `newline="a\nb"`},
+		{[]interface{}{"tab", "a\tb"}, `tab="a\tb"`},
+		{[]interface{}{"unicode", "héllo"}, "unicode=héllo"},
+		{[]interface{}{"err", errors.New("no such file")}, `err="no such file"`},
+		{[]interface{}{"n", 3, "ok", true}, "n=3 ok=true"},
+		{[]interface{}{"odd"}, "odd=(missing)"},
+	}
+
+	for _, tt := range tests {
+		b := configure(t, "info", FormatLogfmt)
+		Info("the msg", tt.kv...)
+
+		line := b.String()
+		if !logfmtTime.MatchString(line) {
+			t.Fatalf("no time at the start of %q", line)
+		}
+		want := `level=info msg="the msg" ` + tt.want + "\n"
+		if got := logfmtTime.ReplaceAllString(line, ""); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b := configure(t, "debug", FormatJSON)
+	With("network", "work").Debug("said \"hi\"\n", "err", errors.New("a \\ b"), "n", 3, "list", []string{"a"})
+
+	line := b.String()
+	if !strings.HasSuffix(line, "}\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("not one line of JSON: %q", line)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("invalid JSON: %v: %q", err, line)
+	}
+	if _, ok := got["time"].(string); !ok {
+		t.Errorf("time = %v, want a string", got["time"])
+	}
+	delete(got, "time")
+
+	want := map[string]interface{}{
+		"level":   "debug",
+		"msg":     "said \"hi\"\n",
+		"network": "work",
+		"err":     `a \ b`,
+		"n":       float64(3),
+		"list":    []interface{}{"a"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, level := range levelNames {
+		t.Run(level, func(t *testing.T) {
+			b := configure(t, level, FormatLogfmt)
+			l := With("k", "v")
+			Debug("debug")
+			Info("info")
+			l.Warn("warn")
+			l.Error("error")
+
+			min, err := ParseLevel(level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([]string, 0)
+			for _, n := range levelNames[min:] {
+				want = append(want, "level="+n)
+			}
+			got := regexp.MustCompile(`level=\w+`).FindAllString(b.String(), -1)
+			if got == nil {
+				got = []string{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("levels written = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	if err := Configure(os.Stderr, "loud", FormatLogfmt); err == nil {
+		t.Error("no error for an invalid level")
+	}
+	if err := Configure(os.Stderr, "info", "xml"); err == nil {
+		t.Error("no error for an invalid format")
+	}
+}
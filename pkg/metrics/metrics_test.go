@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestHistogram(t *testing.T) {
+	r := &Registry{}
+	h := r.NewHistogram("took_seconds", "Time taken.", []float64{.1, .5, 1}, "route")
+	for _, v := range []float64{.05, .1, .3, .7, 2, 3} {
+		h.Observe(v, "/node")
+	}
+	h.Observe(.2, "/graph")
+
+	want := `# HELP took_seconds Time taken.
+# TYPE took_seconds histogram
+took_seconds_bucket{route="/graph",le="0.1"} 0
+took_seconds_bucket{route="/graph",le="0.5"} 1
+took_seconds_bucket{route="/graph",le="1"} 1
+took_seconds_bucket{route="/graph",le="+Inf"} 1
+took_seconds_sum{route="/graph"} 0.2
+took_seconds_count{route="/graph"} 1
+took_seconds_bucket{route="/node",le="0.1"} 2
+took_seconds_bucket{route="/node",le="0.5"} 3
+took_seconds_bucket{route="/node",le="1"} 4
+took_seconds_bucket{route="/node",le="+Inf"} 6
+took_seconds_sum{route="/node"} 6.15
+took_seconds_count{route="/node"} 6
+`
+	if got := write(t, r); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := &Registry{}
+	c := r.NewCounter("requests_total", "Requests.", "method", "status")
+	g := r.NewGauge("nodes", "Nodes.")
+	c.Inc("GET", "200")
+	c.Add(2, "GET", "200")
+	c.Inc("POST", "500")
+	g.Set(3)
+	g.Set(1.5)
+
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 3
+requests_total{method="POST",status="500"} 1
+# HELP nodes Nodes.
+# TYPE nodes gauge
+nodes 1.5
+`
+	if got := write(t, r); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := &Registry{}
+	c := r.NewCounter("escaped_total", "Help with \\ and\na newline.", "value")
+	c.Inc(`back\slash "quoted"` + "\nnewline")
+
+	want := `# HELP escaped_total Help with \\ and\na newline.
+# TYPE escaped_total counter
+escaped_total{value="back\\slash \"quoted\"\nnewline"} 1
+`
+	if got := write(t, r); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestLabelValuesMismatch(t *testing.T) {
+	defer func() {
+		if p := recover(); p == nil || !strings.Contains(p.(string), "has labels") {
+			t.Errorf("panic = %v, want one about the labels", p)
+		}
+	}()
+	r := &Registry{}
+	r.NewCounter("c_total", "C.", "a", "b").Inc("only a")
+}
@@ -0,0 +1,198 @@
+// Package openapi describes the api as an OpenAPI 3 document, built
+// from Operations and the payloads they take and return.
+package openapi
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// Version is the version of the api in the spec
+const Version = "1.0.0"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string                    `json:"url"`
+	Description string                    `json:"description,omitempty"`
+	Variables   map[string]ServerVariable `json:"variables,omitempty"`
+}
+
+type ServerVariable struct {
+	Default     string `json:"default"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, the api only uses GET and POST
+type PathItem struct {
+	Servers []Server         `json:"servers,omitempty"`
+	Get     *OperationObject `json:"get,omitempty"`
+	Post    *OperationObject `json:"post,omitempty"`
+}
+
+func (p *PathItem) set(method string, o *OperationObject) {
+	switch method {
+	case "GET":
+		p.Get = o
+	case "POST":
+		p.Post = o
+	default:
+		panic("openapi: unsupported method " + method)
+	}
+}
+
+type OperationObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+const jsonType = "application/json"
+
+// Spec returns the OpenAPI document of Operations
+func Spec() *Document {
+	s := newSchemas()
+	// every response has the error and its code, whatever its payload
+	errorRef := &Schema{Ref: schemaRefPrefix + s.component(reflect.TypeOf(payloads.Failure{}), true)}
+
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title: "zhuyi",
+			Description: "Networks of markdown notes. Errors are answered with the status of their " +
+				"kind and a machine readable code, see Failure. Bodies and nodes larger than the " +
+				"server allows are answered with 413, clients over their rate limit with 429 and a " +
+				"Retry-After header.",
+			Version: Version,
+		},
+		Servers: []Server{{URL: "/"}},
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+				"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+		Security: []map[string][]string{{"bearer": {}}, {"apiKey": {}}},
+	}
+
+	for _, op := range Operations {
+		item, exists := d.Paths[op.Path]
+		if !exists {
+			item = &PathItem{}
+			if op.Network {
+				item.Servers = []Server{
+					{URL: "/", Description: "the default network"},
+					{URL: "/n/{network}", Description: "a named network", Variables: map[string]ServerVariable{
+						"network": {Default: "default", Description: "name of the network, see /networks"},
+					}},
+				}
+			}
+			d.Paths[op.Path] = item
+		}
+
+		o := &OperationObject{
+			OperationID: lowerFirst(op.ID),
+			Summary:     op.Summary,
+			Responses: map[string]Response{
+				"default": {Description: "error", Content: map[string]MediaType{jsonType: {Schema: errorRef}}},
+			},
+		}
+		if op.Public {
+			o.Security = []map[string][]string{{}}
+		}
+		for _, p := range op.Params {
+			o.Parameters = append(o.Parameters, Parameter{
+				Name:        p.Name,
+				In:          p.In,
+				Description: p.Description,
+				Required:    p.In == inPath,
+				Schema:      &Schema{Type: p.Type, Enum: p.Enum},
+			})
+		}
+
+		switch {
+		case op.Request != nil:
+			o.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				jsonType: {Schema: s.of(reflect.TypeOf(op.Request), false)},
+			}}
+		case op.RequestType != "":
+			o.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				op.RequestType: {Schema: &Schema{Type: "string", Format: "binary"}},
+			}}
+		}
+
+		ok := Response{Description: "ok"}
+		switch {
+		case op.Response != nil:
+			ok.Content = map[string]MediaType{jsonType: {Schema: s.of(reflect.TypeOf(op.Response), true)}}
+		case op.ResponseType != "":
+			ok.Content = map[string]MediaType{op.ResponseType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		}
+		o.Responses["200"] = ok
+
+		item.set(op.Method, o)
+	}
+
+	d.Components.Schemas = s.components
+	return d
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,145 @@
+package openapi
+
+import (
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// Operation is an endpoint of the api. The spec and the client are
+// both generated from Operations, so they can't disagree.
+type Operation struct {
+	// ID is the operationId, and the name of the method of the client
+	ID      string
+	Method  string
+	Path    string
+	Summary string
+	// Network operations are served for the default network at Path,
+	// and for every network under /n/{network}
+	Network bool
+	// Public operations don't need a key
+	Public bool
+	Params []Param
+	// Request is the JSON body, nil if there's none
+	Request interface{}
+	// RequestType is the content type of a body which isn't JSON
+	RequestType string
+	// Response is the JSON response, nil if it isn't JSON
+	Response interface{}
+	// ResponseType is the content type of a response which isn't JSON
+	ResponseType string
+}
+
+// Param is a path or query parameter
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	// Type is string, integer or boolean
+	Type string
+	Enum []string
+}
+
+const (
+	inPath  = "path"
+	inQuery = "query"
+)
+
+var fileParam = Param{Name: "file", In: inPath, Type: "string", Description: "file name of the node, e.g. note.md"}
+var dateParam = Param{Name: "date", In: inPath, Type: "string", Description: "day of the journal, today or yyyy-mm-dd"}
+
+// Operations are every endpoint of the api, in the order they're documented
+var Operations = []Operation{
+	{ID: "Status", Method: "GET", Path: "/status", Public: true,
+		Summary:  "Tells that the api is up",
+		Response: payloads.StatusResponse{}},
+	{ID: "Healthz", Method: "GET", Path: "/healthz", Public: true,
+		Summary:  "Checks that every network can be written to, 503 if not",
+		Response: payloads.HealthResponse{}},
+	{ID: "Readyz", Method: "GET", Path: "/readyz", Public: true,
+		Summary:  "Checks that every network can be written to and is warmed up, 503 if not",
+		Response: payloads.HealthResponse{}},
+	{ID: "OpenAPI", Method: "GET", Path: "/openapi.json", Public: true,
+		Summary:      "Returns this spec",
+		ResponseType: "application/json"},
+	{ID: "Metrics", Method: "GET", Path: "/metrics",
+		Summary:      "Returns the metrics in the Prometheus text format",
+		ResponseType: "text/plain"},
+	{ID: "Networks", Method: "GET", Path: "/networks",
+		Summary:  "Lists the networks the key can use",
+		Response: payloads.NetworksResponse{}},
+
+	{ID: "D3Graph", Method: "GET", Path: "/d3/graph", Network: true,
+		Summary:  "Returns the graph of the network for d3.js",
+		Response: payloads.GraphResponse{}},
+	{ID: "ExportGraph", Method: "GET", Path: "/graph", Network: true,
+		Summary: "Exports the graph of the network",
+		Params: []Param{{Name: "format", In: inQuery, Type: "string", Enum: network.GraphFormats,
+			Description: "format of the graph, graphml if not given"}},
+		ResponseType: "application/octet-stream"},
+	{ID: "Export", Method: "GET", Path: "/export", Network: true,
+		Summary:      "Exports every node as NDJSON, a network.ExportedNode per line",
+		ResponseType: "application/x-ndjson"},
+	{ID: "Import", Method: "POST", Path: "/import", Network: true,
+		Summary: "Imports the nodes of an NDJSON export",
+		Params: []Param{
+			{Name: "conflict", In: inQuery, Type: "string",
+				Enum:        []string{network.ConflictSkip, network.ConflictOverwrite, network.ConflictRename},
+				Description: "what to do with nodes which already exist, skip if not given, overwrite needs the delete scope"},
+			{Name: "dry-run", In: inQuery, Type: "boolean", Description: "only tell what would be done"},
+		},
+		RequestType: "application/x-ndjson",
+		Response:    payloads.ImportResponse{}},
+	{ID: "Unlinked", Method: "GET", Path: "/unlinked", Network: true,
+		Summary:  "Lists the nodes no other node links to",
+		Response: payloads.UnlinkedResponse{}},
+	{ID: "Reachability", Method: "GET", Path: "/reachability", Network: true,
+		Summary:  "Lists the roots every node can be reached from",
+		Response: payloads.ReachabilityResponse{}},
+	{ID: "Diagnostics", Method: "GET", Path: "/diagnostics", Network: true,
+		Summary:  "Finds cycles, dead ends and the depths of the network",
+		Response: payloads.DiagnosticsResponse{}},
+	{ID: "AddNode", Method: "POST", Path: "/node/add", Network: true,
+		Summary:  "Creates a node",
+		Request:  payloads.AppendRequest{},
+		Response: payloads.AppendResponse{}},
+	{ID: "DelNode", Method: "POST", Path: "/node/del", Network: true,
+		Summary:  "Deletes a node, needs the delete scope",
+		Request:  payloads.DelRequest{},
+		Response: payloads.DelResponse{}},
+	{ID: "Node", Method: "GET", Path: "/node/{file}", Network: true,
+		Summary: "Returns a node",
+		Params: []Param{
+			fileParam,
+			{Name: "expand", In: inQuery, Type: "boolean", Description: "replace embeds by what they embed"},
+			{Name: "depth", In: inQuery, Type: "integer", Description: "how deep embeds are expanded"},
+		},
+		Response: payloads.NodeResponse{}},
+	{ID: "AppendNode", Method: "POST", Path: "/node/{file}/append", Network: true,
+		Summary:  "Appends text to a node, under a heading if given",
+		Params:   []Param{fileParam},
+		Request:  payloads.NodeAppendRequest{},
+		Response: payloads.AppendResponse{}},
+	{ID: "Outline", Method: "GET", Path: "/node/{file}/outline", Network: true,
+		Summary:  "Returns the heading tree of a node",
+		Params:   []Param{fileParam},
+		Response: payloads.OutlineResponse{}},
+	{ID: "Journal", Method: "GET", Path: "/journal/{date}", Network: true,
+		Summary:  "Returns the journal node of a day, creating it if needed, needs the write scope",
+		Params:   []Param{dateParam},
+		Response: payloads.NodeResponse{}},
+	{ID: "AppendJournal", Method: "POST", Path: "/journal/{date}", Network: true,
+		Summary:  "Appends an entry to the journal of a day, creating it if needed",
+		Params:   []Param{dateParam},
+		Request:  payloads.JournalRequest{},
+		Response: payloads.AppendResponse{}},
+	{ID: "BrokenAnchors", Method: "GET", Path: "/anchors/broken", Network: true,
+		Summary:  "Lists the links to headings which don't exist",
+		Response: payloads.BrokenAnchorsResponse{}},
+	{ID: "UnlinkedMentions", Method: "GET", Path: "/mentions/unlinked", Network: true,
+		Summary:  "Lists the titles of nodes mentioned without a link",
+		Response: payloads.UnlinkedMentionsResponse{}},
+	{ID: "LinkMention", Method: "POST", Path: "/mentions/link", Network: true,
+		Summary:  "Turns an unlinked mention into a link",
+		Request:  payloads.LinkMentionRequest{},
+		Response: payloads.LinkMentionResponse{}},
+}
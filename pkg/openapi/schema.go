@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the payloads need
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// schemas builds the schemas of Go types as encoding/json encodes
+// them, named types becoming components referred to by $ref
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of returns the schema of t. Fields without omitempty are required,
+// which only holds for what the server encodes, so request bodies
+// are built with required false.
+func (s *schemas) of(t reflect.Type, required bool) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(s.of(t.Elem(), required))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// nil slices are encoded as null
+		return &Schema{Type: "array", Items: s.of(t.Elem(), required), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem(), required)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem(), required), Nullable: true}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return s.object(t, required)
+		}
+		return &Schema{Ref: schemaRefPrefix + s.component(t, required)}
+	}
+	return &Schema{}
+}
+
+// component adds the schema of the named struct t, returning its name
+func (s *schemas) component(t reflect.Type, required bool) string {
+	if name, exists := s.names[t]; exists {
+		return name
+	}
+	name := strings.Title(t.Name())
+	if _, taken := s.components[name]; taken {
+		name = strings.Title(path.Base(t.PkgPath())) + name
+	}
+	s.names[t] = name
+	// added before its fields, which may refer back to it
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t, required)
+	return name
+}
+
+func (s *schemas) object(t reflect.Type, required bool) *Schema {
+	o := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(o, t, required)
+	return o
+}
+
+// fields adds the fields of the struct t to o, those of embedded
+// structs included, as encoding/json does
+func (s *schemas) fields(o *Schema, t reflect.Type, required bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(o, ft, required)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		o.Properties[name] = s.of(ft, required)
+		if required && !strings.Contains(","+opts+",", ",omitempty,") {
+			o.Required = append(o.Required, name)
+		}
+	}
+}
+
+// nullable makes a schema accept null, a $ref can't have siblings
+// so it's wrapped in allOf
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+	}
+	schema.Nullable = true
+	return schema
+}
@@ -0,0 +1,52 @@
+package payloads
+
+import (
+	"errors"
+	"os"
+
+	"github.com/kraem/zhuyi-go/network"
+)
+
+// Codes of the errors of responses, telling clients what
+// went wrong without them having to parse the message
+const (
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeInvalid      = "invalid"
+	CodeInternal     = "internal"
+	CodeUnavailable  = "unavailable"
+	CodeTooLarge     = "too_large"
+	CodeRateLimited  = "rate_limited"
+)
+
+// Failure is embedded in every response. Error is null
+// when the request succeeded, Code is then left out.
+type Failure struct {
+	Error *string `json:"error"`
+	Code  string  `json:"code,omitempty"`
+}
+
+// Fail sets the error of the response
+func (f *Failure) Fail(err error, code string) {
+	errString := err.Error()
+	f.Error = &errString
+	f.Code = code
+}
+
+// ErrorCode returns the code of an error returned by the network
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, network.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, network.ErrConflict), errors.Is(err, os.ErrExist):
+		return CodeConflict
+	case errors.Is(err, network.ErrInvalid):
+		return CodeInvalid
+	case errors.Is(err, network.ErrTooLarge):
+		return CodeTooLarge
+	}
+	return CodeInternal
+}
@@ -1,55 +1,77 @@
 package payloads
 
 import (
-	"fmt"
-
+	"github.com/kraem/zhuyi-go/importer"
 	"github.com/kraem/zhuyi-go/network"
 )
 
 type AppendRequest struct {
 	Payload struct {
-		Title string `json:"title"`
-		Body  string `json:"body"`
+		Title    string   `json:"title"`
+		Body     string   `json:"body"`
+		Template string   `json:"template,omitempty"`
+		Tags     []string `json:"tags,omitempty"`
+		Parent   string   `json:"parent,omitempty"`
+		Heading  string   `json:"heading,omitempty"`
+		Position string   `json:"position,omitempty"`
 	} `json:"payload"`
 }
 
 type AppendResponse struct {
 	Payload *appendPayload `json:"payload,omitempty"`
-	Error   *string        `json:"error"`
+	Failure
 }
 
 type appendPayload struct {
-	FileName *string `json:"file_name,omitempty"`
+	FileName *string  `json:"file_name,omitempty"`
+	Changed  []string `json:"changed_files,omitempty"`
 }
 
-func NewAppendResponse(fn *string, err error) AppendResponse {
-	var errStringPtr *string
-	var payload *appendPayload
-	if err != nil {
-		es := fmt.Sprintf("%v", err.Error())
-		errStringPtr = &es
+func NewAppendResponse(fn *string, changed []string, err error) AppendResponse {
+	resp := AppendResponse{
+		Payload: &appendPayload{
+			FileName: fn,
+			Changed:  changed,
+		},
 	}
-	payload = &appendPayload{
-		FileName: fn,
-	}
-	return AppendResponse{
-		Payload: payload,
-		Error:   errStringPtr,
+	if err != nil {
+		resp.Fail(err, ErrorCode(err))
 	}
+	return resp
+}
+
+// ErrorResponse is the response of requests failing before
+// they get to the handler, e.g. when they aren't authorized.
+type ErrorResponse struct {
+	Payload struct{} `json:"payload"`
+	Failure
+}
+
+type NetworkInfo struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default,omitempty"`
+	Prefix  string `json:"prefix"`
+}
+
+type NetworksResponse struct {
+	Payload struct {
+		Networks []NetworkInfo `json:"networks"`
+	} `json:"payload"`
+	Failure
 }
 
 type GraphResponse struct {
 	Payload struct {
 		Graph *network.D3jsGraph `json:"graph,omitempty"`
 	} `json:"payload"`
-	Error *string `json:"error"`
+	Failure
 }
 
 type UnlinkedResponse struct {
 	Payload struct {
 		Nodes []network.Node `json:"unlinked_nodes"`
 	} `json:"payload"`
-	Error *string `json:"error"`
+	Failure
 }
 
 type DelRequest struct {
@@ -59,7 +81,7 @@ type DelRequest struct {
 }
 
 type DelResponse struct {
-	Error *string `json:"error"`
+	Failure
 }
 
 type StatusResponse struct {
@@ -67,3 +89,98 @@ type StatusResponse struct {
 		Status string `json:"status"`
 	} `json:"payload"`
 }
+
+// HealthResponse is the response of /healthz and /readyz, Networks
+// holding the status of every network, ok or what's wrong with it
+type HealthResponse struct {
+	Payload struct {
+		Status   string            `json:"status"`
+		Networks map[string]string `json:"networks"`
+	} `json:"payload"`
+	Failure
+}
+
+type UnlinkedMentionsResponse struct {
+	Payload struct {
+		Nodes []network.NodeMentions `json:"nodes"`
+	} `json:"payload"`
+	Failure
+}
+
+type LinkMentionRequest struct {
+	Payload struct {
+		FileName string `json:"file_name"`
+		Target   string `json:"target"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"payload"`
+}
+
+type LinkMentionResponse struct {
+	Failure
+}
+
+type ReachabilityResponse struct {
+	Payload struct {
+		Nodes []network.Reachability `json:"nodes"`
+	} `json:"payload"`
+	Failure
+}
+
+type DiagnosticsResponse struct {
+	Payload struct {
+		Diagnostics *network.DiagnosticsReport `json:"diagnostics,omitempty"`
+	} `json:"payload"`
+	Failure
+}
+
+type ImportReportResponse struct {
+	Payload struct {
+		DryRun bool             `json:"dry_run"`
+		Report *importer.Report `json:"report,omitempty"`
+	} `json:"payload"`
+	Failure
+}
+
+type ImportResponse struct {
+	Payload struct {
+		DryRun bool                  `json:"dry_run"`
+		Result *network.ImportResult `json:"result,omitempty"`
+	} `json:"payload"`
+	Failure
+}
+
+type OutlineResponse struct {
+	Payload struct {
+		FileName string             `json:"file_name"`
+		Headings []*network.Heading `json:"headings"`
+	} `json:"payload"`
+	Failure
+}
+
+type BrokenAnchorsResponse struct {
+	Payload struct {
+		Links []network.SectionLink `json:"links"`
+	} `json:"payload"`
+	Failure
+}
+
+type NodeResponse struct {
+	Payload struct {
+		Node *network.NodeContent `json:"node,omitempty"`
+	} `json:"payload"`
+	Failure
+}
+
+type JournalRequest struct {
+	Payload struct {
+		Text string `json:"text"`
+	} `json:"payload"`
+}
+
+type NodeAppendRequest struct {
+	Payload struct {
+		Text    string `json:"text"`
+		Heading string `json:"heading,omitempty"`
+	} `json:"payload"`
+}
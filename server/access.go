@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// requestIDHeader carries the id of a request, taken from the
+// request if the client sent a valid one, in the response
+const requestIDHeader = "X-Request-ID"
+
+// unmatchedRoute is the route of requests no route matches, so
+// scanning for paths can't make up as many routes as it likes
+const unmatchedRoute = "unmatched"
+
+var requestIDExtractor = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// AccessLog wraps h, serving the routes of router, giving every request
+// an id, which the logger in its context adds to what's logged about
+// it, and logging and observing the latency of every request.
+func AccessLog(router *mux.Router, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDExtractor.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		logger := log.With("request_id", id)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r.WithContext(log.NewContext(r.Context(), logger)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		route := matchedRoute(router, r)
+		d := time.Since(start)
+		status := strconv.Itoa(rec.status)
+		requestsTotal.Inc(route, r.Method, status)
+		requestSeconds.Observe(d.Seconds(), route, r.Method)
+
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", float64(d.Microseconds())/1000,
+			"remote", r.RemoteAddr,
+		)
+	})
+}
+
+// matchedRoute returns the template of the route of the request,
+// without the prefix of named networks
+func matchedRoute(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return unmatchedRoute
+	}
+	t, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return strings.TrimPrefix(t, NetworkPrefix)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder remembers the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// apiKeyHeader is an alternative to Authorization: Bearer <key>
+const apiKeyHeader = "X-API-Key"
+
+// Scopes a key can be granted
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDelete = "delete"
+)
+
+var allScopes = []string{ScopeRead, ScopeWrite, ScopeDelete}
+
+// routeScopes are the scopes of the routes which don't
+// need read for GET and write for everything else
+var routeScopes = map[string]string{
+	"/node/del": ScopeDelete,
+	// reading the journal of a day creates it if it doesn't exist
+	"/journal/{date}": ScopeWrite,
+}
+
+// publicRoutes can be used without a key
+var publicRoutes = map[string]bool{
+	"/status":  true,
+	"/healthz": true,
+	"/readyz":  true,
+	// the api isn't a secret
+	"/openapi.json": true,
+}
+
+// Key is a client allowed to use the api. A scope is granted for
+// every network, e.g. read, or for a single one, e.g. work:read.
+type Key struct {
+	Name   string
+	Hash   [sha256.Size]byte
+	Scopes map[string]bool
+}
+
+// allows tells if the key has scope in network
+func (k *Key) allows(network, scope string) bool {
+	return k.Scopes[scope] || k.Scopes[network+":"+scope]
+}
+
+// anyScope tells if the key can do anything in network
+func (k *Key) anyScope(network string) bool {
+	for _, s := range allScopes {
+		if k.allows(network, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Auth authenticates requests by bearer tokens or api keys. A nil
+// Auth, when neither tokens nor keys are configured, lets everyone in.
+type Auth struct {
+	keys []Key
+}
+
+// NewAuth returns the Auth of the static tokens and the keys of the
+// keys file, or nil if there are neither. The scopes of the keys can
+// only refer to networks.
+func NewAuth(tokens []string, keysFile string, networks []string) (*Auth, error) {
+	a := &Auth{}
+	for i, t := range tokens {
+		a.keys = append(a.keys, Key{
+			Name:   fmt.Sprintf("token-%d", i+1),
+			Hash:   sha256.Sum256([]byte(t)),
+			Scopes: scopeSet(allScopes),
+		})
+	}
+
+	if keysFile != "" {
+		f, err := os.Open(keysFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		keys, err := ParseKeys(f, networks)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", keysFile, err)
+		}
+		a.keys = append(a.keys, keys...)
+	}
+
+	if len(a.keys) == 0 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// ParseKeys parses a keys file, a key per line of name, hex encoded
+// sha256 of the key and comma separated scopes, optionally prefixed
+// by the network they're limited to:
+//
+//	# name  sha256                                                            scopes
+//	laptop  9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08  read,work:write
+//
+// The hash of a key is printed by: printf %s "$key" | sha256sum
+func ParseKeys(r io.Reader, networks []string) ([]Key, error) {
+	keys := make([]Key, 0)
+	names := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		fields := strings.Fields(l)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected name, sha256 and scopes", line)
+		}
+		if names[fields[0]] {
+			return nil, fmt.Errorf("line %d: duplicate key name: %v", line, fields[0])
+		}
+		names[fields[0]] = true
+
+		k := Key{Name: fields[0]}
+		h, err := hex.DecodeString(fields[1])
+		if err != nil || len(h) != sha256.Size {
+			return nil, fmt.Errorf("line %d: invalid sha256: %v", line, fields[1])
+		}
+		copy(k.Hash[:], h)
+
+		scopes := strings.Split(fields[2], ",")
+		for _, s := range scopes {
+			if i := strings.Index(s, ":"); i >= 0 {
+				if !scopeSet(networks)[s[:i]] {
+					return nil, fmt.Errorf("line %d: no such network: %v", line, s[:i])
+				}
+				s = s[i+1:]
+			}
+			if !scopeSet(allScopes)[s] {
+				return nil, fmt.Errorf("line %d: invalid scope: %v, expected %v", line, s, strings.Join(allScopes, ", "))
+			}
+		}
+		k.Scopes = scopeSet(scopes)
+
+		keys = append(keys, k)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func scopeSet(scopes []string) map[string]bool {
+	set := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		set[s] = true
+	}
+	return set
+}
+
+// authenticate returns the key of the request, nil if it has none
+// or it doesn't match any.
+func (a *Auth) authenticate(r *http.Request) *Key {
+	secret := r.Header.Get(apiKeyHeader)
+	if h := r.Header.Get("Authorization"); secret == "" && h != "" {
+		if len(h) > len("Bearer ") && strings.EqualFold(h[:len("Bearer ")], "Bearer ") {
+			secret = strings.TrimSpace(h[len("Bearer "):])
+		}
+	}
+	if secret == "" {
+		return nil
+	}
+
+	// compare every key, so the time taken doesn't tell which matched
+	hash := sha256.Sum256([]byte(secret))
+	var found *Key
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], a.keys[i].Hash[:]) == 1 {
+			found = &a.keys[i]
+		}
+	}
+	return found
+}
+
+// Middleware rejects requests without a valid key with 401, and
+// those whose key lacks the scope of the route in the network of
+// the request with 403. Routes without a network only need a key.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		if a == nil || r.Method == "OPTIONS" || publicRoutes[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		k := a.authenticate(r)
+		if k == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zhuyi"`)
+			writeError(w, r, &payloads.ErrorResponse{}, &httpError{
+				status: http.StatusUnauthorized,
+				code:   payloads.CodeUnauthorized,
+				msg:    "missing or invalid api key",
+			})
+			return
+		}
+
+		if !networkFreeRoutes[route] {
+			scope, name := routeScope(r, route), networkName(r)
+			if !k.allows(name, scope) {
+				writeError(w, r, &payloads.ErrorResponse{}, &httpError{
+					status: http.StatusForbidden,
+					code:   payloads.CodeForbidden,
+					msg:    fmt.Sprintf("key %v lacks the %v scope in network %v", k.Name, scope, name),
+				})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyContextKey, k)))
+	})
+}
+
+// keyOf returns the key of the request, nil when auth is disabled
+func keyOf(r *http.Request) *Key {
+	k, _ := r.Context().Value(keyContextKey).(*Key)
+	return k
+}
+
+// routeTemplate returns the template of the route of the request,
+// without the prefix of named networks
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if t, err := route.GetPathTemplate(); err == nil {
+			return strings.TrimPrefix(t, NetworkPrefix)
+		}
+	}
+	return r.URL.Path
+}
+
+// routeScope returns the scope needed for the request to route, the
+// template of its route, so routes of named networks are matched too
+func routeScope(r *http.Request, route string) string {
+	// overwriting replaces nodes, which is as good as deleting them
+	if route == "/import" && r.URL.Query().Get("conflict") == network.ConflictOverwrite {
+		return ScopeDelete
+	}
+	if s, exists := routeScopes[route]; exists {
+		return s
+	}
+	if r.Method == "GET" || r.Method == "HEAD" {
+		return ScopeRead
+	}
+	return ScopeWrite
+}
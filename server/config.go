@@ -1,38 +1,63 @@
 package server
 
 import (
-	"os"
+	"fmt"
 
-	"github.com/kraem/zhuyi-go/pkg/env"
-	"github.com/kraem/zhuyi-go/pkg/log"
 	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/config"
+	"github.com/kraem/zhuyi-go/pkg/log"
 )
 
-const LISTEN_ADDR = "LISTEN_ADDR"
-
 type Server struct {
-	CfgNetwork *network.Config
-	Cfg       *config
-}
+	// Networks are the networks served by name, see Network
+	Networks map[string]*network.Config
+	Cfg      *config.Config
+	Auth     *Auth
+	CORS     *CORS
+	Limiter  *Limiter
 
-type config struct {
-	Addr string
+	// shutdown is set once the server is shutting down
+	shutdown int32
 }
 
-func NewServer() *Server {
-	c, err := network.NewConfig()
+// NewServer sets up the networks, auth and CORS of cfg
+func NewServer(cfg *config.Config) (*Server, error) {
+	s := &Server{
+		Networks: make(map[string]*network.Config),
+		Cfg:      cfg,
+		CORS: &CORS{
+			Origins:     cfg.CORS.Origins,
+			Methods:     cfg.CORS.Methods,
+			Headers:     cfg.CORS.Headers,
+			Credentials: cfg.CORS.Credentials,
+			MaxAge:      cfg.CORS.MaxAge,
+		},
+	}
+
+	names := make([]string, 0)
+	for _, n := range cfg.AllNetworks() {
+		c, err := network.NewConfigFrom(n, cfg.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("network %v: %v", n.Name, err)
+		}
+		s.Networks[n.Name] = c
+		names = append(names, n.Name)
+	}
+
+	a, err := NewAuth(cfg.Auth.Tokens, cfg.Auth.KeysFile, names)
 	if err != nil {
-		log.LogError(err)
-		os.Exit(1)
+		return nil, err
 	}
-	return &Server{
-		CfgNetwork: c,
-		Cfg:       Config(),
+	if a == nil {
+		log.Warn("neither auth.tokens nor auth.keys_file set, the api is open to anyone")
 	}
-}
+	s.Auth = a
 
-func Config() *config {
-	return &config{
-		Addr: env.GetEnvOrExit(LISTEN_ADDR),
+	l, err := NewLimiter(cfg.Limits, cfg.RateLimit, a)
+	if err != nil {
+		return nil, err
 	}
+	s.Limiter = l
+
+	return s, nil
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// CORS is the cross origin policy of the api
+type CORS struct {
+	// Origins allowed to use the api, * for any
+	Origins []string
+	Methods []string
+	Headers []string
+	// Credentials allows cookies and such, which browsers don't send
+	// to a wildcard origin, see config.Validate
+	Credentials bool
+	// MaxAge is how many seconds browsers may cache a preflight, 0 to not say
+	MaxAge int
+}
+
+func (c *CORS) allowedOrigin(origin string) string {
+	for _, o := range c.Origins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+func (c *CORS) allowedMethod(method string) bool {
+	for _, m := range c.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler wraps the router, adding the CORS headers to requests from
+// allowed origins and answering the preflights of every route of the
+// router, with the methods the route is registered for.
+func (c *CORS) Handler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := c.allowedOrigin(origin)
+
+		preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+		if !preflight {
+			if allowed != "" {
+				w.Header().Set("Access-Control-Allow-Origin", allowed)
+				if c.Credentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		methods := c.routeMethods(router, r)
+		if len(methods) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		if allowed == "" || !c.allowedMethod(r.Header.Get("Access-Control-Request-Method")) {
+			// no CORS headers tells the browser no
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.Headers, ", "))
+		if c.Credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if c.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// routeMethods returns the allowed methods the path of the request is routed for
+func (c *CORS) routeMethods(router *mux.Router, r *http.Request) []string {
+	methods := make([]string, 0, len(c.Methods))
+	for _, m := range c.Methods {
+		req := r.Clone(r.Context())
+		req.Method = strings.ToUpper(m)
+		var match mux.RouteMatch
+		if router.Match(req, &match) && match.MatchErr == nil {
+			methods = append(methods, req.Method)
+		}
+	}
+	return methods
+}
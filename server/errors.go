@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// failer is a response with an embedded payloads.Failure
+type failer interface {
+	Fail(err error, code string)
+}
+
+// httpError is an error of the request itself rather than
+// of the network, e.g. a body which isn't valid JSON
+type httpError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+// badRequest is the error of a request which can't be decoded, unless
+// that's down to reading it failing with an httpError, e.g. a body
+// larger than allowed
+func badRequest(err error) error {
+	var he *httpError
+	if errors.As(err, &he) {
+		return err
+	}
+	return &httpError{status: http.StatusBadRequest, code: payloads.CodeBadRequest, msg: err.Error()}
+}
+
+// codeStatus are the statuses of the codes of network errors
+var codeStatus = map[string]int{
+	payloads.CodeNotFound: http.StatusNotFound,
+	payloads.CodeConflict: http.StatusConflict,
+	payloads.CodeInvalid:  http.StatusUnprocessableEntity,
+	payloads.CodeTooLarge: http.StatusRequestEntityTooLarge,
+	payloads.CodeInternal: http.StatusInternalServerError,
+}
+
+// errorStatus returns the status and code of the response to err
+func errorStatus(err error) (int, string) {
+	var he *httpError
+	if errors.As(err, &he) {
+		return he.status, he.code
+	}
+	code := payloads.ErrorCode(err)
+	return codeStatus[code], code
+}
+
+// writeError sets the error of resp and writes it with the status
+// of the kind of the error. Only internal errors are logged, with
+// the request id, the others are the fault of the client.
+func writeError(w http.ResponseWriter, r *http.Request, resp failer, err error) {
+	status, code := errorStatus(err)
+	resp.Fail(err, code)
+	writeJSON(w, status, resp)
+	if status == http.StatusInternalServerError {
+		log.FromContext(r.Context()).Error(err.Error(), "route", routeTemplate(r))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,87 +1,219 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 
+	"github.com/gorilla/mux"
+	"github.com/kraem/zhuyi-go/network"
 	"github.com/kraem/zhuyi-go/pkg/log"
 	"github.com/kraem/zhuyi-go/pkg/payloads"
 )
 
-func AddNodeHandlerOptions(s *Server) http.Handler {
+func AddNodeHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		setupResponse(&w, r)
+
+		w.Header().Set("Content-Type", "application/json")
+		var resp payloads.AppendResponse
+
+		var payloadIncoming payloads.AppendRequest
+
+		err := json.NewDecoder(r.Body).Decode(&payloadIncoming)
+		if err != nil {
+			writeError(w, r, &resp, badRequest(err))
+			return
+		}
+
+		p := payloadIncoming.Payload
+		nodeFileName, changed, err := s.Network(r).CreateNodeFrom(network.NodeOptions{
+			Title:          p.Title,
+			Body:           p.Body,
+			Template:       p.Template,
+			Tags:           p.Tags,
+			Parent:         p.Parent,
+			ParentHeading:  p.Heading,
+			ParentPosition: p.Position,
+		})
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		resp = payloads.NewAppendResponse(&nodeFileName, changed, nil)
+
+		json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func AddNodeHandler(s *Server) http.Handler {
+func DelNodeHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		setupResponse(&w, r)
-		if (*r).Method == "OPTIONS" {
+		w.Header().Set("Content-Type", "application/json")
+		var resp payloads.DelResponse
+
+		var payloadIncoming payloads.DelRequest
+		err := json.NewDecoder(r.Body).Decode(&payloadIncoming)
+		if err != nil {
+			writeError(w, r, &resp, badRequest(err))
 			return
 		}
 
+		err = s.Network(r).DelNode(payloadIncoming.Payload.FileName)
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func UnlinkedHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var resp payloads.UnlinkedResponse
+
+		ns, err := s.Network(r).UnlinkedNodes()
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		resp.Payload.Nodes = ns
+
 		w.Header().Set("Content-Type", "application/json")
-		var resp payloads.AppendResponse
+		json.NewEncoder(w).Encode(resp)
+	})
+}
 
-		var payloadIncoming payloads.AppendRequest
+func GraphHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		err := json.NewDecoder(r.Body).Decode(&payloadIncoming)
+		var resp payloads.GraphResponse
+
+		g, err := s.Network(r).CreateD3jsGraph()
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			var fn *string
-			resp = payloads.NewAppendResponse(fn, err)
-			json.NewEncoder(w).Encode(resp)
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		resp.Payload.Graph = g
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// ExportGraphHandler writes the graph as ?format=graphml, gexf or dot
+func ExportGraphHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var resp payloads.GraphResponse
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = network.GraphML
+		}
+		if err := network.ValidGraphFormat(format); err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		var b bytes.Buffer
+		if err := s.Network(r).ExportGraph(&b, format); err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", network.GraphContentType(format))
+		w.Write(b.Bytes())
+	})
+}
+
+// ExportHandler streams every node as NDJSON
+func ExportHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		// the status is sent with the first node,
+		// so failing halfway can only be logged
+		if err := s.Network(r).ExportNodes(w); err != nil {
 			log.LogError(err)
+		}
+	})
+}
+
+// ImportHandler recreates the nodes of an NDJSON export in the request
+// body, ?conflict=skip (default), overwrite or rename, and ?dry-run=true
+func ImportHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		var resp payloads.ImportResponse
+
+		q := r.URL.Query()
+		policy := q.Get("conflict")
+		if policy == "" {
+			policy = network.ConflictSkip
+		}
+		resp.Payload.DryRun = q.Get("dry-run") == "true"
+
+		if err := network.ValidConflictPolicy(policy); err != nil {
+			writeError(w, r, &resp, err)
 			return
 		}
 
-		nodeFileName, err := s.CfgNetwork.CreateNode(payloadIncoming.Payload.Title, payloadIncoming.Payload.Body)
+		res, err := s.Network(r).ImportNodesFrom(r.Body, policy, resp.Payload.DryRun)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			var fn *string
-			resp = payloads.NewAppendResponse(fn, err)
-			json.NewEncoder(w).Encode(resp)
-			log.LogError(err)
+			writeError(w, r, &resp, err)
 			return
 		}
 
-		resp = payloads.NewAppendResponse(&nodeFileName, nil)
+		resp.Payload.Result = res
 
 		json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func DelNodeHandler(s *Server) http.Handler {
+func UnlinkedMentionsHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		setupResponse(&w, r)
-		if (*r).Method == "OPTIONS" {
+		var resp payloads.UnlinkedMentionsResponse
+
+		ns, err := s.Network(r).UnlinkedMentions()
+		if err != nil {
+			writeError(w, r, &resp, err)
 			return
 		}
 
+		resp.Payload.Nodes = ns
+
 		w.Header().Set("Content-Type", "application/json")
-		var resp payloads.DelResponse
+		json.NewEncoder(w).Encode(resp)
+	})
+}
 
-		var payloadIncoming payloads.DelRequest
+func LinkMentionHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		var resp payloads.LinkMentionResponse
+
+		var payloadIncoming payloads.LinkMentionRequest
 		err := json.NewDecoder(r.Body).Decode(&payloadIncoming)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			errString := err.Error()
-			resp.Error = &errString
-			json.NewEncoder(w).Encode(resp)
-			log.LogError(err)
+			writeError(w, r, &resp, badRequest(err))
 			return
 		}
 
-		err = s.CfgNetwork.DelNode(payloadIncoming.Payload.FileName)
+		p := payloadIncoming.Payload
+		err = s.Network(r).LinkMention(p.FileName, p.Target, p.Line, p.Column)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			errString := err.Error()
-			resp.Error = &errString
-			json.NewEncoder(w).Encode(resp)
-			log.LogError(err)
+			writeError(w, r, &resp, err)
 			return
 		}
 
@@ -89,87 +221,204 @@ func DelNodeHandler(s *Server) http.Handler {
 	})
 }
 
-func UnlinkedHandler(s *Server) http.Handler {
+func ReachabilityHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		setupResponse(&w, r)
-		if (*r).Method == "OPTIONS" {
+		var resp payloads.ReachabilityResponse
+
+		ns, err := s.Network(r).Reachability()
+		if err != nil {
+			writeError(w, r, &resp, err)
 			return
 		}
 
-		var resp payloads.UnlinkedResponse
+		resp.Payload.Nodes = ns
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
 
-		ns, err := s.CfgNetwork.UnlinkedNodes()
+func DiagnosticsHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var resp payloads.DiagnosticsResponse
+
+		d, err := s.Network(r).Diagnostics()
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			errString := err.Error()
-			resp.Error = &errString
-			json.NewEncoder(w).Encode(resp)
-			log.LogError(err)
+			writeError(w, r, &resp, err)
 			return
 		}
 
-		resp.Payload.Nodes = ns
+		resp.Payload.Diagnostics = d
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func GraphHandler(s *Server) http.Handler {
+func NodeHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		setupResponse(&w, r)
-		if (*r).Method == "OPTIONS" {
+		var resp payloads.NodeResponse
+
+		fileName := mux.Vars(r)["file"]
+		nc, err := readNode(s.Network(r), fileName, r.URL.Query())
+		if err != nil {
+			writeError(w, r, &resp, err)
 			return
 		}
 
-		var resp payloads.GraphResponse
+		resp.Payload.Node = nc
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// readNode reads the node, expanding its embeds
+// when asked to with ?expand=true[&depth=n]
+func readNode(c *network.Config, fileName string, q url.Values) (*network.NodeContent, error) {
+	if q.Get("expand") != "true" {
+		return c.ReadNode(fileName)
+	}
+	depth := network.DefaultEmbedDepth
+	if d := q.Get("depth"); d != "" {
+		var err error
+		if depth, err = strconv.Atoi(d); err != nil {
+			return nil, badRequest(fmt.Errorf("invalid depth: %v", d))
+		}
+	}
+	return c.ExpandNode(fileName, depth)
+}
 
-		g, err := s.CfgNetwork.CreateD3jsGraph()
+func AppendNodeHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		var resp payloads.AppendResponse
+
+		var payloadIncoming payloads.NodeAppendRequest
+		err := json.NewDecoder(r.Body).Decode(&payloadIncoming)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			errString := err.Error()
-			resp.Error = &errString
-			json.NewEncoder(w).Encode(resp)
-			log.LogError(err)
+			writeError(w, r, &resp, badRequest(err))
 			return
 		}
 
-		resp.Payload.Graph = g
+		fileName := mux.Vars(r)["file"]
+		p := payloadIncoming.Payload
+		err = s.Network(r).AppendNode(fileName, p.Heading, p.Text)
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		resp = payloads.NewAppendResponse(&fileName, []string{fileName}, nil)
+
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func JournalHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var resp payloads.NodeResponse
+
+		day, err := network.ParseJournalDate(mux.Vars(r)["date"])
+		if err == nil {
+			resp.Payload.Node, err = s.Network(r).Journal(day)
+		}
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func StatusHandler(a *Server) http.Handler {
+func AppendJournalHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		setupResponse(&w, r)
-		if (*r).Method == "OPTIONS" {
+
+		w.Header().Set("Content-Type", "application/json")
+		var resp payloads.AppendResponse
+
+		var payloadIncoming payloads.JournalRequest
+		err := json.NewDecoder(r.Body).Decode(&payloadIncoming)
+		if err != nil {
+			writeError(w, r, &resp, badRequest(err))
 			return
 		}
-		var resp payloads.StatusResponse
 
-		resp.Payload.Status = "API is up and running"
+		var fileName string
+		var changed []string
+		day, err := network.ParseJournalDate(mux.Vars(r)["date"])
+		if err == nil {
+			fileName, changed, err = s.Network(r).AppendJournal(day, payloadIncoming.Payload.Text)
+		}
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		resp = payloads.NewAppendResponse(&fileName, changed, nil)
+
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func OutlineHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var resp payloads.OutlineResponse
+
+		fileName := mux.Vars(r)["file"]
+		hs, err := s.Network(r).Outline(fileName)
+		if err != nil {
+			writeError(w, r, &resp, err)
+			return
+		}
+
+		resp.Payload.FileName = fileName
+		resp.Payload.Headings = hs
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func NotImplemented(a *Server) http.Handler {
+func BrokenAnchorsHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		setupResponse(&w, r)
-		if (*r).Method == "OPTIONS" {
+
+		var resp payloads.BrokenAnchorsResponse
+
+		sls, err := s.Network(r).BrokenAnchors()
+		if err != nil {
+			writeError(w, r, &resp, err)
 			return
 		}
-		w.Write([]byte("Not Implemented"))
+
+		resp.Payload.Links = sls
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func StatusHandler(a *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp payloads.StatusResponse
+
+		resp.Payload.Status = "API is up and running"
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func setupResponse(w *http.ResponseWriter, req *http.Request) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-	(*w).Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+func NotImplemented(a *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Not Implemented"))
+	})
 }
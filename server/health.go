@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/log"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+const statusOK = "ok"
+
+// WarmUp warms up every network, see network.Config.WarmUp. Networks
+// failing to are logged, and stay not ready.
+func (s *Server) WarmUp() {
+	for name, c := range s.Networks {
+		go func(name string, c *network.Config) {
+			if err := c.WarmUp(); err != nil {
+				log.Error("warming up failed", "network", name, "err", err)
+			}
+		}(name, c)
+	}
+}
+
+// drain makes the server not ready, so load balancers stop sending
+// it requests during timeouts.drain before it shuts down
+func (s *Server) drain() {
+	atomic.StoreInt32(&s.shutdown, 1)
+}
+
+func (s *Server) shuttingDown() bool {
+	return atomic.LoadInt32(&s.shutdown) == 1
+}
+
+// HealthzHandler answers 200 if every network can be written to, 503 if not
+func HealthzHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, s, func(c *network.Config) error {
+			return c.CheckWritable()
+		})
+	})
+}
+
+// ReadyzHandler answers 200 if every network can be written to and is
+// warmed up, 503 if not or if the server is shutting down
+func ReadyzHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, s, func(c *network.Config) error {
+			if s.shuttingDown() {
+				return fmt.Errorf("shutting down")
+			}
+			if !c.Ready() {
+				return fmt.Errorf("warming up")
+			}
+			return c.CheckWritable()
+		})
+	})
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, s *Server, check func(c *network.Config) error) {
+	var resp payloads.HealthResponse
+	resp.Payload.Networks = make(map[string]string, len(s.Networks))
+
+	failed := make([]string, 0)
+	for name, c := range s.Networks {
+		if err := check(c); err != nil {
+			resp.Payload.Networks[name] = err.Error()
+			failed = append(failed, name)
+			continue
+		}
+		resp.Payload.Networks[name] = statusOK
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		resp.Payload.Status = "unavailable"
+		writeError(w, r, &resp, &httpError{
+			status: http.StatusServiceUnavailable,
+			code:   payloads.CodeUnavailable,
+			msg:    "unavailable networks: " + strings.Join(failed, ", "),
+		})
+		return
+	}
+
+	resp.Payload.Status = statusOK
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/config"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// Classes of routes, each rate limited on its own
+const (
+	classRead  = "read"
+	classWrite = "write"
+	classBulk  = "bulk"
+)
+
+// bulkRoutes move whole networks, see config.RateLimit
+var bulkRoutes = map[string]bool{
+	"/import": true,
+	"/export": true,
+}
+
+func routeClass(route, method string) string {
+	switch {
+	case bulkRoutes[route]:
+		return classBulk
+	case method == "GET" || method == "HEAD":
+		return classRead
+	}
+	return classWrite
+}
+
+// Limiter rejects requests of clients over the rate of the class of
+// the route with 429, and bodies larger than MaxBodySize with 413.
+type Limiter struct {
+	// MaxBodySize is the most bytes of a request body, 0 for no limit
+	MaxBodySize int64
+
+	classes map[string]*bucketSet
+	// auth tells the clients with a key apart, nil when auth is disabled
+	auth *Auth
+}
+
+// NewLimiter sets up the limits and rates configured, nil if there are
+// none. Clients with a key of auth are limited by it, the others by
+// their address.
+func NewLimiter(limits config.Limits, rates config.RateLimit, auth *Auth) (*Limiter, error) {
+	l := &Limiter{
+		MaxBodySize: int64(limits.MaxBodySize),
+		classes:     make(map[string]*bucketSet),
+		auth:        auth,
+	}
+	for class, s := range map[string]string{
+		classRead:  rates.Read,
+		classWrite: rates.Write,
+		classBulk:  rates.Bulk,
+	} {
+		rate, err := config.ParseRate(s)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit.%v: %v", class, err)
+		}
+		if rate.N > 0 {
+			l.classes[class] = newBucketSet(rate)
+		}
+	}
+	if l.MaxBodySize == 0 && len(l.classes) == 0 {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// Middleware has to come before the auth middleware, so requests
+// without a valid key, which it rejects, are limited as well.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l == nil || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		class := routeClass(routeTemplate(r), r.Method)
+		if b, exists := l.classes[class]; exists {
+			if wait, ok := b.take(l.clientOf(r), time.Now()); !ok {
+				rateLimited.Inc(class)
+				secs := int(math.Ceil(wait.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(secs))
+				writeError(w, r, &payloads.ErrorResponse{}, &httpError{
+					status: http.StatusTooManyRequests,
+					code:   payloads.CodeRateLimited,
+					msg:    fmt.Sprintf("too many %v requests, retry in %ds", class, secs),
+				})
+				return
+			}
+		}
+
+		if l.MaxBodySize > 0 && r.Body != nil {
+			if r.ContentLength > l.MaxBodySize {
+				writeError(w, r, &payloads.ErrorResponse{}, bodyTooLarge(l.MaxBodySize))
+				return
+			}
+			r.Body = &limitedBody{ReadCloser: r.Body, left: l.MaxBodySize, max: l.MaxBodySize}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientOf tells clients apart by their key, or else their address.
+// Every client of the unix socket shares its limits.
+func (l *Limiter) clientOf(r *http.Request) string {
+	if l.auth != nil {
+		if k := l.auth.authenticate(r); k != nil {
+			return "key:" + k.Name
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "addr:" + r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+func bodyTooLarge(max int64) error {
+	return &httpError{
+		status: http.StatusRequestEntityTooLarge,
+		code:   payloads.CodeTooLarge,
+		msg:    fmt.Sprintf("request body is larger than %d bytes", max),
+	}
+}
+
+// limitedBody fails reads past max bytes with a 413 httpError, which
+// the handlers answer with as it's returned by the decoding
+type limitedBody struct {
+	io.ReadCloser
+	left int64
+	max  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return 0, bodyTooLarge(b.max)
+	}
+	// one byte more than is left tells if the body goes on
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		return n - 1, bodyTooLarge(b.max)
+	}
+	return n, err
+}
+
+// bucketSet holds a token bucket of every client. A bucket holds up
+// to rate.N tokens, refilled at N per Per, and a request takes one.
+type bucketSet struct {
+	rate    config.Rate
+	perSec  float64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newBucketSet(rate config.Rate) *bucketSet {
+	return &bucketSet{
+		rate:    rate,
+		perSec:  float64(rate.N) / rate.Per.Seconds(),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// take takes a token of the bucket of client, or tells how
+// long it is until there's one if the bucket is empty
+func (s *bucketSet) take(client string, now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	b, exists := s.buckets[client]
+	if !exists {
+		b = &bucket{tokens: float64(s.rate.N), last: now}
+		s.buckets[client] = b
+	}
+	s.refill(b, now)
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / s.perSec * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func (s *bucketSet) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(s.rate.N), b.tokens+elapsed*s.perSec)
+		b.last = now
+	}
+}
+
+// sweep drops the buckets which are full again, which are no
+// different from new ones, at most once every Per
+func (s *bucketSet) sweep(now time.Time) {
+	if now.Sub(s.swept) < s.rate.Per {
+		return
+	}
+	s.swept = now
+	for client, b := range s.buckets {
+		s.refill(b, now)
+		if b.tokens >= float64(s.rate.N) {
+			delete(s.buckets, client)
+		}
+	}
+}
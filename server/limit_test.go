@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kraem/zhuyi-go/pkg/config"
+)
+
+func TestLimiterLimitsUnauthorized(t *testing.T) {
+	s, _ := testServer(t)
+	l, err := NewLimiter(config.Limits{}, config.RateLimit{Read: "3/h"}, s.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Limiter = l
+	router := NewRouter(s)
+
+	for _, token := range []string{"", "wrong", "guess"} {
+		rec := serve(router, "GET", "/node/index.md", token, nil)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("token %q: status = %d, want 401", token, rec.Code)
+		}
+	}
+	// the bad tokens used up the bucket of the address
+	rec := serve(router, "GET", "/node/index.md", "another guess", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After")
+	}
+
+	// clients with a key have buckets of their own
+	for i := 0; i < 3; i++ {
+		if rec := serve(router, "GET", "/node/index.md", testToken, nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d with a key: status = %d, want 200", i, rec.Code)
+		}
+	}
+	if rec := serve(router, "GET", "/node/index.md", testToken, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 once the key's bucket is empty", rec.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+	"github.com/kraem/zhuyi-go/pkg/metrics"
+)
+
+var (
+	requestsTotal = metrics.NewCounter("zhuyi_http_requests_total",
+		"Requests handled.", "route", "method", "status")
+	requestSeconds = metrics.NewHistogram("zhuyi_http_request_duration_seconds",
+		"Time taken to handle requests.", metrics.DefaultBuckets, "route", "method")
+	rateLimited = metrics.NewCounter("zhuyi_http_rate_limited_total",
+		"Requests rejected for being over the rate limit.", "class")
+	nodeCount = metrics.NewGauge("zhuyi_nodes",
+		"Nodes in the network.", "network")
+)
+
+// MetricsHandler writes the metrics in the Prometheus text format,
+// counting the nodes of every network first
+func MetricsHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, c := range s.Networks {
+			n, err := c.CountNodes()
+			if err != nil {
+				log.FromContext(r.Context()).Error("counting nodes failed", "network", name, "err", err)
+				continue
+			}
+			nodeCount.Set(float64(n), name)
+		}
+
+		w.Header().Set("Content-Type", metrics.ContentType)
+		metrics.Default.WriteTo(w)
+	})
+}
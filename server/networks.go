@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/kraem/zhuyi-go/network"
+	"github.com/kraem/zhuyi-go/pkg/config"
+	"github.com/kraem/zhuyi-go/pkg/payloads"
+)
+
+// NetworkPrefix is the prefix of the routes of a named network,
+// the routes without it use the default network
+const NetworkPrefix = "/n/{network}"
+
+// networkFreeRoutes don't use a network
+var networkFreeRoutes = map[string]bool{
+	"/status":   true,
+	"/networks": true,
+	"/healthz":  true,
+	"/readyz":   true,
+	"/metrics":  true,
+	// the spec covers every network
+	"/openapi.json": true,
+}
+
+type contextKey int
+
+const (
+	networkContextKey contextKey = iota
+	keyContextKey
+)
+
+// networkName returns the name of the network the request is for
+func networkName(r *http.Request) string {
+	if n := mux.Vars(r)["network"]; n != "" {
+		return n
+	}
+	return config.DefaultNetwork
+}
+
+// NetworkMiddleware looks up the network of the request,
+// answering 404 if there's no such network.
+func (s *Server) NetworkMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if networkFreeRoutes[routeTemplate(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := networkName(r)
+		c, exists := s.Networks[name]
+		if !exists {
+			writeError(w, r, &payloads.ErrorResponse{}, &httpError{
+				status: http.StatusNotFound,
+				code:   payloads.CodeNotFound,
+				msg:    fmt.Sprintf("no such network: %v", name),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), networkContextKey, c)))
+	})
+}
+
+// Network returns the network of the request, see NetworkMiddleware
+func (s *Server) Network(r *http.Request) *network.Config {
+	c, _ := r.Context().Value(networkContextKey).(*network.Config)
+	return c
+}
+
+// NetworksHandler lists the networks the key of the request can use
+func NetworksHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp payloads.NetworksResponse
+
+		k := keyOf(r)
+		resp.Payload.Networks = make([]payloads.NetworkInfo, 0, len(s.Networks))
+		for name := range s.Networks {
+			if s.Auth != nil && (k == nil || !k.anyScope(name)) {
+				continue
+			}
+			prefix := "/n/" + name
+			resp.Payload.Networks = append(resp.Payload.Networks, payloads.NetworkInfo{
+				Name:    name,
+				Default: name == config.DefaultNetwork,
+				Prefix:  prefix,
+			})
+		}
+		sort.Slice(resp.Payload.Networks, func(i, j int) bool {
+			return resp.Payload.Networks[i].Name < resp.Payload.Networks[j].Name
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/kraem/zhuyi-go/pkg/openapi"
+)
+
+// OpenAPIHandler serves the OpenAPI spec of the api
+func OpenAPIHandler(s *Server) http.Handler {
+	spec := openapi.Spec()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, spec)
+	})
+}
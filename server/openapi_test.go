@@ -0,0 +1,379 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kraem/zhuyi-go/pkg/config"
+	"github.com/kraem/zhuyi-go/pkg/openapi"
+)
+
+const testToken = "test-token"
+
+// testNotes are written to every network of testServer. Index mentions
+// the title of other.md on line 6, column 12.
+var testNotes = map[string]string{
+	"index.md": `---
+title: Index
+root: true
+---
+links to [other](other.md) and [a heading](other.md#nope)
+mentioning other note without a link
+`,
+	"other.md": `---
+title: Other note
+---
+## Part
+back to [index](index.md)
+`,
+	"deleteme.md": `---
+title: Delete me
+---
+`,
+}
+
+// testServer serves a default network and one called work, both with
+// testNotes, to clients with testToken
+func testServer(t *testing.T) (*Server, *mux.Router) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.NetworkPath = t.TempDir()
+	cfg.Networks = []config.Network{{Name: "work", NetworkPath: t.TempDir()}}
+	cfg.Auth.Tokens = []string{testToken}
+	cfg.RateLimit = config.RateLimit{}
+	for _, dir := range []string{cfg.NetworkPath, cfg.Networks[0].NetworkPath} {
+		for f, content := range testNotes {
+			if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range s.Networks {
+		if err := c.WarmUp(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, NewRouter(s)
+}
+
+// testRequests are the bodies of the operations which take one
+var testRequests = map[string]string{
+	"Import":        `{"file":"imported.md","front_matter":null,"body":"imported"}` + "\n",
+	"AddNode":       `{"payload":{"title":"new node","body":"body","tags":["a"]}}`,
+	"DelNode":       `{"payload":{"file_name":"deleteme.md"}}`,
+	"AppendNode":    `{"payload":{"text":"appended"}}`,
+	"AppendJournal": `{"payload":{"text":"entry"}}`,
+	"LinkMention":   `{"payload":{"file_name":"index.md","target":"other.md","line":6,"column":12}}`,
+}
+
+// testParams are the values of path parameters
+var testParams = map[string]string{
+	"file": "index.md",
+	"date": "2021-02-02",
+}
+
+func serve(router http.Handler, method, path, token string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOperationsConformToSpec(t *testing.T) {
+	spec := openapi.Spec()
+
+	for _, prefix := range []string{"", "/n/work"} {
+		_, router := testServer(t)
+		for _, op := range openapi.Operations {
+			if prefix != "" && !op.Network {
+				continue
+			}
+			path := prefix + op.Path
+			for name, v := range testParams {
+				path = strings.Replace(path, "{"+name+"}", v, 1)
+			}
+			t.Run(op.Method+" "+path, func(t *testing.T) {
+				var body io.Reader
+				if op.Request != nil || op.RequestType != "" {
+					b, exists := testRequests[op.ID]
+					if !exists {
+						t.Fatalf("no test request for %v", op.ID)
+					}
+					body = strings.NewReader(b)
+				}
+
+				rec := serve(router, op.Method, path, testToken, body)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+				}
+				checkResponse(t, spec, op, rec)
+			})
+		}
+	}
+}
+
+func TestErrorsConformToSpec(t *testing.T) {
+	spec := openapi.Spec()
+	_, router := testServer(t)
+
+	tests := []struct {
+		id     string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"Node", "/node/nope.md", testToken, "", http.StatusNotFound},
+		{"Node", "/node/index.txt", testToken, "", http.StatusUnprocessableEntity},
+		{"Node", "/n/nope/node/index.md", testToken, "", http.StatusNotFound},
+		{"Node", "/node/index.md", "", "", http.StatusUnauthorized},
+		{"Node", "/node/index.md", "wrong", "", http.StatusUnauthorized},
+		{"AddNode", "/node/add", testToken, "{bad", http.StatusBadRequest},
+		{"DelNode", "/node/del", testToken, `{"payload":{"file_name":"nope.md"}}`, http.StatusNotFound},
+		{"Journal", "/journal/2026-13-01", testToken, "", http.StatusUnprocessableEntity},
+		{"LinkMention", "/mentions/link", testToken,
+			`{"payload":{"file_name":"index.md","target":"other.md","line":5,"column":1}}`, http.StatusConflict},
+		{"Import", "/import?conflict=nope", testToken, "", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			op := operation(t, tt.id)
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			rec := serve(router, op.Method, tt.path, tt.token, body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			checkResponse(t, spec, op, rec)
+
+			var f struct {
+				Error *string `json:"error"`
+				Code  string  `json:"code"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &f); err != nil || f.Error == nil || f.Code == "" {
+				t.Errorf("error response without error and code: %s", rec.Body)
+			}
+		})
+	}
+}
+
+// TestRoutesAreOperations checks that the router serves exactly the
+// operations of the spec, so neither can be changed without the other
+func TestRoutesAreOperations(t *testing.T) {
+	_, router := testServer(t)
+
+	routes := make([]string, 0)
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// the subrouter of the named networks
+			return nil
+		}
+		for _, m := range methods {
+			routes = append(routes, m+" "+tmpl)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := make([]string, 0)
+	for _, op := range openapi.Operations {
+		ops = append(ops, op.Method+" "+op.Path)
+		if op.Network {
+			ops = append(ops, op.Method+" "+NetworkPrefix+op.Path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(ops)
+	if strings.Join(routes, "\n") != strings.Join(ops, "\n") {
+		t.Errorf("routes:\n%v\n\noperations:\n%v", strings.Join(routes, "\n"), strings.Join(ops, "\n"))
+	}
+}
+
+func operation(t *testing.T, id string) openapi.Operation {
+	t.Helper()
+	for _, op := range openapi.Operations {
+		if op.ID == id {
+			return op
+		}
+	}
+	t.Fatalf("no operation %v", id)
+	return openapi.Operation{}
+}
+
+// checkResponse validates the response against the one of the spec for
+// its status, or else the default one
+func checkResponse(t *testing.T, spec *openapi.Document, op openapi.Operation, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	item := spec.Paths[op.Path]
+	if item == nil {
+		t.Fatalf("%v isn't in the spec", op.Path)
+	}
+	o := item.Get
+	if op.Method == "POST" {
+		o = item.Post
+	}
+	if o == nil {
+		t.Fatalf("%v %v isn't in the spec", op.Method, op.Path)
+	}
+
+	resp, documented := o.Responses[strconv.Itoa(rec.Code)]
+	if !documented {
+		resp = o.Responses["default"]
+	}
+	contentType := rec.Header().Get("Content-Type")
+	if len(resp.Content) == 0 {
+		if rec.Body.Len() > 0 {
+			t.Errorf("undocumented body: %s", rec.Body)
+		}
+		return
+	}
+
+	media, exists := resp.Content[contentType]
+	if !exists {
+		// binary responses vary in their type, e.g. the graph
+		// formats or text/plain; version=0.0.4 of the metrics
+		for _, m := range resp.Content {
+			if m.Schema != nil && m.Schema.Format == "binary" {
+				media, exists = m, true
+			}
+		}
+	}
+	if !exists {
+		t.Fatalf("content type %q isn't documented for %d", contentType, rec.Code)
+	}
+	if media.Schema == nil || media.Schema.Format == "binary" {
+		return
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON: %v: %s", err, rec.Body)
+	}
+	// responses have to match their payload exactly, errors may
+	// have the empty payload of the response next to the error
+	strict := documented
+	if err := validate(spec, media.Schema, v, "$", strict); err != nil {
+		t.Errorf("%v: %s", err, rec.Body)
+	}
+}
+
+// validate checks v, decoded JSON, against the schema. Properties
+// the schema doesn't have are only allowed if strict isn't set.
+func validate(spec *openapi.Document, s *openapi.Schema, v interface{}, path string, strict bool) error {
+	if s.Ref != "" {
+		ref, exists := spec.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !exists {
+			return fmt.Errorf("%v: unknown $ref %v", path, s.Ref)
+		}
+		return validate(spec, ref, v, path, strict)
+	}
+	if v == nil {
+		if !s.Nullable && (s.Type != "" || len(s.AllOf) > 0) {
+			return fmt.Errorf("%v: null isn't allowed", path)
+		}
+		return nil
+	}
+	for _, sub := range s.AllOf {
+		if err := validate(spec, sub, v, path, strict); err != nil {
+			return err
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v: %v isn't an object", path, v)
+		}
+		for _, r := range s.Required {
+			if _, exists := o[r]; !exists {
+				return fmt.Errorf("%v: %v is missing", path, r)
+			}
+		}
+		for k, x := range o {
+			p, exists := s.Properties[k]
+			switch {
+			case exists:
+			case s.AdditionalProperties != nil:
+				p = s.AdditionalProperties
+			case strict:
+				return fmt.Errorf("%v: unknown property %v", path, k)
+			default:
+				continue
+			}
+			if err := validate(spec, p, x, path+"."+k, strict); err != nil {
+				return err
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v: %v isn't an array", path, v)
+		}
+		for i, x := range a {
+			if err := validate(spec, s.Items, x, fmt.Sprintf("%v[%d]", path, i), strict); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v: %v isn't a string", path, v)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return fmt.Errorf("%v: %v isn't one of %v", path, str, s.Enum)
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%v: %v isn't an integer", path, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%v: %v isn't a number", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%v: %v isn't a boolean", path, v)
+		}
+	}
+	return nil
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewRouter routes every endpoint of the api, see openapi.Operations,
+// behind the limit, auth and network middlewares
+func NewRouter(s *Server) *mux.Router {
+	r := mux.NewRouter()
+	r.Handle("/status", StatusHandler(s)).Methods("GET")
+	r.Handle("/networks", NetworksHandler(s)).Methods("GET")
+	r.Handle("/healthz", HealthzHandler(s)).Methods("GET")
+	r.Handle("/readyz", ReadyzHandler(s)).Methods("GET")
+	r.Handle("/metrics", MetricsHandler(s)).Methods("GET")
+	r.Handle("/openapi.json", OpenAPIHandler(s)).Methods("GET")
+	// every network under /n/{network}, the default one at the root as well
+	networkRoutes(r.PathPrefix(NetworkPrefix).Subrouter(), s)
+	networkRoutes(r, s)
+	r.Use(s.Limiter.Middleware, s.Auth.Middleware, s.NetworkMiddleware)
+	return r
+}
+
+func networkRoutes(r *mux.Router, s *Server) {
+	r.Handle("/d3/graph", GraphHandler(s)).Methods("GET")
+	r.Handle("/graph", ExportGraphHandler(s)).Methods("GET")
+	r.Handle("/export", ExportHandler(s)).Methods("GET")
+	r.Handle("/import", ImportHandler(s)).Methods("POST")
+	r.Handle("/unlinked", UnlinkedHandler(s)).Methods("GET")
+	r.Handle("/reachability", ReachabilityHandler(s)).Methods("GET")
+	r.Handle("/diagnostics", DiagnosticsHandler(s)).Methods("GET")
+	r.Handle("/node/add", AddNodeHandler(s)).Methods("POST")
+	r.Handle("/node/del", DelNodeHandler(s)).Methods("POST")
+	r.Handle("/node/{file}", NodeHandler(s)).Methods("GET")
+	r.Handle("/node/{file}/append", AppendNodeHandler(s)).Methods("POST")
+	r.Handle("/node/{file}/outline", OutlineHandler(s)).Methods("GET")
+	r.Handle("/journal/{date}", JournalHandler(s)).Methods("GET")
+	r.Handle("/journal/{date}", AppendJournalHandler(s)).Methods("POST")
+	r.Handle("/anchors/broken", BrokenAnchorsHandler(s)).Methods("GET")
+	r.Handle("/mentions/unlinked", UnlinkedMentionsHandler(s)).Methods("GET")
+	r.Handle("/mentions/link", LinkMentionHandler(s)).Methods("POST")
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// Serve serves h on the configured address and unix socket until
+// SIGTERM or SIGINT. It then answers /readyz with 503 for
+// timeouts.drain, or until a second signal, stops accepting
+// connections and waits for the requests in flight, writes to the
+// networks included, for at most timeouts.shutdown before giving up
+// on them.
+func (s *Server) Serve(h http.Handler) error {
+	t := s.Cfg.Timeouts
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: t.Read,
+		ReadTimeout:       t.Read,
+		WriteTimeout:      t.Write,
+		IdleTimeout:       t.Idle,
+	}
+
+	listeners, err := s.listen(srv)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l listener) {
+			log.Info("listening", "addr", l.Addr())
+			if l.tls {
+				errs <- srv.ServeTLS(l, "", "")
+				return
+			}
+			errs <- srv.Serve(l)
+		}(l)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-errs:
+		srv.Close()
+		return err
+	case sig := <-stop:
+		log.Info("shutting down", "signal", sig)
+	}
+
+	s.drain()
+	if t.Drain > 0 {
+		log.Info("draining", "for", t.Drain)
+		select {
+		case <-time.After(t.Drain):
+		case <-stop:
+		}
+	}
+
+	ctx := context.Background()
+	if t.Shutdown > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Shutdown)
+		defer cancel()
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutting down: %v", err)
+	}
+	return nil
+}
+
+type listener struct {
+	net.Listener
+	tls bool
+}
+
+// listen opens the tcp address, with tls if it's configured, and the
+// unix socket. Listeners already opened are closed if one fails.
+func (s *Server) listen(srv *http.Server) ([]listener, error) {
+	listeners := make([]listener, 0, 2)
+	fail := func(err error) ([]listener, error) {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return nil, err
+	}
+
+	if s.Cfg.Listen != "" {
+		l, err := net.Listen("tcp", s.Cfg.Listen)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, listener{Listener: l})
+		if c := s.Cfg.TLS; c.Cert != "" {
+			if srv.TLSConfig, err = tlsConfig(c.Cert, c.Key, c.Reload); err != nil {
+				return fail(err)
+			}
+			listeners[len(listeners)-1].tls = true
+		}
+	}
+
+	if s.Cfg.Unix.Path != "" {
+		l, err := listenUnix(s.Cfg.Unix.Path)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, listener{Listener: l})
+		mode, err := s.Cfg.Unix.FileMode()
+		if err == nil {
+			err = os.Chmod(s.Cfg.Unix.Path, mode)
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	return listeners, nil
+}
+
+// listenUnix listens on the socket at path, removing the socket a
+// previous run didn't get to. Anything else at path is left alone.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%v exists and isn't a socket", path)
+		}
+		// a socket nothing accepts on is stale
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%v is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kraem/zhuyi-go/pkg/log"
+)
+
+// certCheckInterval is how often the files of a reloaded
+// certificate are checked for changes, at most
+const certCheckInterval = 10 * time.Second
+
+// tlsConfig returns the tls config of the certificate in certFile and
+// keyFile, which is loaded again when they change if reload is set
+func tlsConfig(certFile, keyFile string, reload bool) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if !reload {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+		return cfg, nil
+	}
+
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+	cr.checked = time.Now()
+	cfg.GetCertificate = cr.getCertificate
+	return cfg, nil
+}
+
+// certReloader loads the certificate again, on a handshake, when
+// its files have changed since it was loaded
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.Mutex
+	cert *tls.Certificate
+	// modTime is that of the latest changed file when cert was loaded
+	modTime time.Time
+	checked time.Time
+}
+
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if time.Since(cr.checked) >= certCheckInterval {
+		cr.checked = time.Now()
+		// the old certificate keeps being served until the new one
+		// loads, e.g. while only one of the files has been replaced
+		if err := cr.reload(); err != nil {
+			log.LogError(err)
+		}
+	}
+	return cr.cert, nil
+}
+
+func (cr *certReloader) reload() error {
+	modTime := time.Time{}
+	for _, f := range []string{cr.certFile, cr.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if cr.cert != nil && !modTime.After(cr.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading %v: %v", cr.certFile, err)
+	}
+	if cr.cert != nil {
+		log.Info("reloaded the certificate", "cert", cr.certFile)
+	}
+	cr.cert, cr.modTime = &cert, modTime
+	return nil
+}